@@ -0,0 +1,251 @@
+package shandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultQueueSize is the number of records queued by AsyncWriter by default.
+const defaultQueueSize = 1024
+
+// ErrWriterClosed is returned by writing to a closed AsyncWriter.
+var ErrWriterClosed = errors.New("shandler: writer is closed")
+
+// OverflowPolicy decides what AsyncWriter does when its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the writer until there's room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the record being written.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued record to make room.
+	OverflowDropOldest
+)
+
+// AsyncWriter queues the records written to it into a bounded ring, they're
+// written to the underlying writer in batches by a background goroutine, so
+// a slow output doesn't stall the goroutines logging. Pass it to WithWriter,
+// and Close it on shutdown to write the queued records.
+//
+//	w := NewAsyncWriter(os.Stderr, WithOverflow(OverflowDropOldest))
+//	defer w.Close()
+//	slog.SetDefault(slog.New(NewTextHandler(WithWriter(w))))
+type AsyncWriter struct {
+	w      io.Writer
+	policy OverflowPolicy
+	onDrop func(dropped uint64)
+
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	ring     []*Buffer
+	head     int
+	n        int
+	writing  bool            // a batch taken from ring is being written
+	waiters  []chan struct{} // closed when the queue is drained, refer to Flush
+	closed   bool
+	err      error // the last error of w, it's reported by Flush and Close
+
+	dropped  atomic.Uint64
+	reported uint64 // dropped count passed to onDrop, only used by run
+	done     chan struct{}
+}
+
+// AsyncOption configures an AsyncWriter.
+type AsyncOption func(*AsyncWriter)
+
+// WithQueueSize sets the number of records the queue holds, default is 1024.
+func WithQueueSize(size int) AsyncOption {
+	return func(a *AsyncWriter) {
+		if size > 0 {
+			a.ring = make([]*Buffer, size)
+		}
+	}
+}
+
+// WithOverflow sets what to do when the queue is full, default is OverflowBlock.
+func WithOverflow(policy OverflowPolicy) AsyncOption {
+	return func(a *AsyncWriter) {
+		a.policy = policy
+	}
+}
+
+// WithDropReport calls fn from the background goroutine with the number of
+// the records dropped since the last call, after a batch is written.
+func WithDropReport(fn func(dropped uint64)) AsyncOption {
+	return func(a *AsyncWriter) {
+		a.onDrop = fn
+	}
+}
+
+// NewAsyncWriter creates an AsyncWriter of w and starts its background goroutine.
+func NewAsyncWriter(w io.Writer, opts ...AsyncOption) *AsyncWriter {
+	a := &AsyncWriter{
+		w:    w,
+		ring: make([]*Buffer, defaultQueueSize),
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.notEmpty.L = &a.mu
+	a.notFull.L = &a.mu
+	go a.run()
+	return a
+}
+
+// Write queues a copy of p, it never returns the error of the underlying writer.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for !a.closed && a.n == len(a.ring) {
+		switch a.policy {
+		case OverflowDropNewest:
+			a.dropped.Add(1)
+			return len(p), nil
+		case OverflowDropOldest:
+			a.ring[a.head].Free()
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.n--
+			a.dropped.Add(1)
+		default:
+			a.notFull.Wait()
+		}
+	}
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+
+	buf := NewBuffer()
+	_, _ = buf.Write(p)
+	a.ring[(a.head+a.n)%len(a.ring)] = buf
+	a.n++
+	a.notEmpty.Signal()
+	return len(p), nil
+}
+
+// Dropped returns the number of records dropped by the overflow policy.
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Flush waits until the queued records are written or ctx is done,
+// it returns the last error of the underlying writer since the previous
+// Flush if there's any.
+func (a *AsyncWriter) Flush(ctx context.Context) error {
+	a.mu.Lock()
+	if a.n == 0 && !a.writing {
+		defer a.mu.Unlock()
+		return a.takeErr()
+	}
+	ch := make(chan struct{})
+	a.waiters = append(a.waiters, ch)
+	a.mu.Unlock()
+
+	select {
+	case <-ch:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.takeErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// takeErr returns and clears the error, a.mu must be locked.
+func (a *AsyncWriter) takeErr() error {
+	err := a.err
+	a.err = nil
+	return err
+}
+
+// Close writes the queued records and stops the background goroutine, the
+// underlying writer isn't closed. The writes after Close return ErrWriterClosed.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+	<-a.done
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.takeErr()
+}
+
+// IsTerminal reports whether the underlying writer is a terminal,
+// so the output is colored in ColorAuto mode, refer to Terminal.
+func (a *AsyncWriter) IsTerminal() bool {
+	return isTerminal(a.w)
+}
+
+// run writes the queued records in batches until the writer is closed.
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	batch := make([]*Buffer, 0, len(a.ring))
+	out := NewBuffer()
+	defer out.Free()
+	for {
+		a.mu.Lock()
+		for a.n == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if a.n == 0 {
+			a.drained()
+			a.mu.Unlock()
+			return
+		}
+		for ; a.n > 0; a.n-- {
+			batch = append(batch, a.ring[a.head])
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+		}
+		a.writing = true
+		a.notFull.Broadcast()
+		a.mu.Unlock()
+
+		out.Reset()
+		for i, buf := range batch {
+			_, _ = out.Write(*buf)
+			buf.Free()
+			batch[i] = nil
+		}
+		batch = batch[:0]
+		_, err := a.w.Write(*out)
+		a.reportDropped()
+
+		a.mu.Lock()
+		if err != nil {
+			a.err = err
+		}
+		a.writing = false
+		if a.n == 0 {
+			a.drained()
+		}
+		a.mu.Unlock()
+	}
+}
+
+// drained wakes up the Flush waiting for the queue, a.mu must be locked.
+func (a *AsyncWriter) drained() {
+	for _, ch := range a.waiters {
+		close(ch)
+	}
+	a.waiters = a.waiters[:0]
+}
+
+func (a *AsyncWriter) reportDropped() {
+	if a.onDrop == nil {
+		return
+	}
+	if dropped := a.dropped.Load(); dropped > a.reported {
+		a.onDrop(dropped - a.reported)
+		a.reported = dropped
+	}
+}
@@ -0,0 +1,119 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"log/slog"
+)
+
+// gateWriter blocks every Write until it's released.
+type gateWriter struct {
+	entered chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	writes  []string
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{entered: make(chan struct{}, 16), release: make(chan struct{})}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	g.entered <- struct{}{}
+	<-g.release
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writes = append(g.writes, string(p))
+	return len(p), nil
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		writes  []string
+		dropped uint64
+	}{
+		{OverflowDropNewest, []string{"a", "bc"}, 1},
+		{OverflowDropOldest, []string{"a", "cd"}, 1},
+		{OverflowBlock, []string{"a", "bc", "d"}, 0},
+	}
+	for _, tt := range tests {
+		g := newGateWriter()
+		var reported uint64
+		w := NewAsyncWriter(g, WithQueueSize(2), WithOverflow(tt.policy),
+			WithDropReport(func(n uint64) { reported += n }))
+		_, _ = w.Write([]byte("a"))
+		<-g.entered // a is being written, the queue is empty
+		_, _ = w.Write([]byte("b"))
+		_, _ = w.Write([]byte("c"))
+
+		written := make(chan struct{})
+		go func() {
+			_, _ = w.Write([]byte("d"))
+			close(written)
+		}()
+		if tt.policy == OverflowBlock {
+			select {
+			case <-written:
+				t.Fatalf("%d: the write isn't blocked by the full queue", tt.policy)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := w.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%d: got %v, want the deadline of the blocked flush", tt.policy, err)
+		}
+		cancel()
+
+		close(g.release)
+		<-written
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := g.writes; len(got) != len(tt.writes) || got[0] != tt.writes[0] || got[1] != tt.writes[1] {
+			t.Errorf("%d: got writes %q, want %q", tt.policy, got, tt.writes)
+		}
+		if got := w.Dropped(); got != tt.dropped || reported != tt.dropped {
+			t.Errorf("%d: got dropped %d and reported %d, want %d", tt.policy, got, reported, tt.dropped)
+		}
+		if _, err := w.Write([]byte("e")); !errors.Is(err, ErrWriterClosed) {
+			t.Errorf("%d: got %v, want ErrWriterClosed", tt.policy, err)
+		}
+	}
+}
+
+type failWriter struct{ err error }
+
+func (f failWriter) Write([]byte) (int, error) { return 0, f.err }
+
+func TestAsyncWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewAsyncWriter(&buf)
+	logger := slog.New(NewTextHandler(WithWriter(w), WithTimeFormat("-")))
+	for i := 0; i < 100; i++ {
+		logger.Info("hello", "i", i)
+	}
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := bytes.Count(buf.Bytes(), []byte("\n")); got != 100 {
+		t.Errorf("got %d records, want 100", got)
+	}
+	_ = w.Close()
+
+	failed := errors.New("failed")
+	w = NewAsyncWriter(failWriter{failed})
+	_, _ = w.Write([]byte("a"))
+	if err := w.Flush(context.Background()); err != failed {
+		t.Errorf("got %v, want %v", err, failed)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("got %v, the error is reported once", err)
+	}
+}
@@ -1,7 +1,9 @@
 package shandler
 
 import (
+	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	"unicode"
@@ -10,7 +12,13 @@ import (
 	"log/slog"
 )
 
-const groupKeySep = '.'
+const (
+	groupKeySep = '.'
+	callerSep   = '/'
+
+	// prefixKey is the key used by structured output for the handler's prefix.
+	prefixKey = "prefix"
+)
 
 var groupPool = sync.Pool{New: func() any {
 	s := make([]string, 0, 10)
@@ -27,6 +35,7 @@ type Builder interface {
 	appendPrefix()
 	appendMessage()
 	appendAttrs()
+	preformat(attrs []slog.Attr)
 	output() *Buffer
 }
 
@@ -60,6 +69,44 @@ func (b *baseBuilder) resolve(a slog.Attr) slog.Attr {
 	return a
 }
 
+// source returns the location of the record's caller.
+func (b *baseBuilder) source() slog.Source {
+	fs := runtime.CallersFrames([]uintptr{b.r.PC})
+	f, _ := fs.Next()
+	return slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+}
+
+// caller returns src as function:line, the function is
+// trimmed to package.FunctionName unless fullCaller is enabled.
+func (b *baseBuilder) caller(src slog.Source) string {
+	caller := src.Function
+	if !b.h.fullCaller {
+		var founded int
+		idx := strings.LastIndexFunc(caller, func(r rune) bool {
+			if r == callerSep {
+				founded++
+			}
+			if founded == 2 {
+				return true
+			}
+			return false
+		})
+		caller = caller[idx+1:]
+	}
+	return caller + ":" + strconv.Itoa(src.Line)
+}
+
+// replaceBuiltin passes the built-in attribute a to the Replacer with no groups,
+// it reports false if the attribute is discarded.
+func (b *baseBuilder) replaceBuiltin(a slog.Attr) (slog.Attr, bool) {
+	if b.h.replacer == nil {
+		return a, true
+	}
+	a = b.h.replacer(nil, a)
+	a.Value = a.Value.Resolve()
+	return a, a.Key != ""
+}
+
 func (b *baseBuilder) quote(str string) string {
 	if !needsQuoting(str) {
 		return str
@@ -75,10 +122,8 @@ func needsQuoting(s string) bool {
 	for i := 0; i < len(s); {
 		b := s[i]
 		if b < utf8.RuneSelf {
-			if b == '\t' || b == '\n' {
-				return false
-			}
-			if b != '\\' && (b == '=' || !safeSet[b]) {
+			// the space is safe in JSON only
+			if b != '\\' && (b == ' ' || b == '=' || !safeSet[b]) {
 				return true
 			}
 			i++
@@ -95,6 +140,12 @@ func needsQuoting(s string) bool {
 
 func (b *baseBuilder) free() {
 	b.buf.Free()
+	b.freeGroups()
+}
+
+// freeGroups returns the groups slice to the pool without releasing buf,
+// it's used when buf is owned by the handler, e.g. while preformatting.
+func (b *baseBuilder) freeGroups() {
 	if gs := b.groups; gs != nil {
 		*gs = (*gs)[:0]
 		groupPool.Put(gs)
@@ -0,0 +1,100 @@
+package shandler
+
+import (
+	"os"
+	"strings"
+)
+
+// ColorMode decides whether the output is colored,
+// ColorAlways and ColorNever take precedence over the environment.
+type ColorMode int
+
+const (
+	// ColorAuto colors the output if it's a terminal, the environment
+	// variables NO_COLOR, FORCE_COLOR, CLICOLOR_FORCE and CLICOLOR are respected.
+	ColorAuto ColorMode = iota
+	// ColorAlways colors the output even if it's not a terminal, e.g. `| less -R`.
+	ColorAlways
+	// ColorNever never colors the output.
+	ColorNever
+)
+
+// Terminal can be implemented by the writers which wrap a terminal
+// but aren't a File, so that they're colored in ColorAuto mode.
+type Terminal interface {
+	IsTerminal() bool
+}
+
+// colorEnv is the decision made by the environment variables.
+type colorEnv int
+
+const (
+	colorEnvUnset colorEnv = iota
+	colorEnvForced
+	colorEnvDisabled
+)
+
+// lookupColorEnv reads the color conventions of the environment:
+//   - NO_COLOR (https://no-color.org) disables colors if it's not empty.
+//   - FORCE_COLOR (https://force-color.org) forces colors if it's not empty, "0" and "false" disable them,
+//     the values "1", "2" and "3" also select the 16, 256 and 24-bit color profile.
+//   - CLICOLOR_FORCE (https://bixense.com/clicolors) forces colors unless it's "0".
+//   - CLICOLOR=0 disables colors unless they're forced.
+func lookupColorEnv() (colorEnv, ColorProfile) {
+	if os.Getenv("NO_COLOR") != "" {
+		return colorEnvDisabled, ProfileAuto
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" {
+		switch strings.ToLower(force) {
+		case "0", "false":
+			return colorEnvDisabled, ProfileAuto
+		case "1":
+			return colorEnvForced, ProfileANSI
+		case "2":
+			return colorEnvForced, ProfileANSI256
+		case "3":
+			return colorEnvForced, ProfileTrueColor
+		default:
+			return colorEnvForced, ProfileAuto
+		}
+	}
+	if force := os.Getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return colorEnvForced, ProfileAuto
+	}
+	if os.Getenv("CLICOLOR") == "0" {
+		return colorEnvDisabled, ProfileAuto
+	}
+	return colorEnvUnset, ProfileAuto
+}
+
+// forcedByEnv reports whether the colors are forced by the environment.
+func forcedByEnv() bool {
+	env, _ := lookupColorEnv()
+	return env == colorEnvForced
+}
+
+// isColored reports whether the output is colored, the profile
+// selected by the environment is returned along with it.
+func (h *baseHandler) isColored() (bool, ColorProfile) {
+	switch h.colorMode {
+	case ColorAlways:
+		return true, ProfileAuto
+	case ColorNever:
+		return false, ProfileAuto
+	}
+
+	switch env, profile := lookupColorEnv(); env {
+	case colorEnvForced:
+		return true, profile
+	case colorEnvDisabled:
+		return false, ProfileAuto
+	}
+	return h.isTTY(), ProfileAuto
+}
+
+// WithColorMode sets whether the output is colored, default is ColorAuto.
+func WithColorMode(mode ColorMode) Option {
+	return func(cfg *baseHandler) {
+		cfg.colorMode = mode
+	}
+}
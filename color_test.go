@@ -0,0 +1,90 @@
+package shandler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"log/slog"
+)
+
+type fakeTerminal struct {
+	bytes.Buffer
+}
+
+func (*fakeTerminal) IsTerminal() bool { return true }
+
+func TestColorMode(t *testing.T) {
+	for _, key := range []string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR"} {
+		t.Setenv(key, "")
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		mode ColorMode
+		term bool
+		want bool
+	}{
+		{"pipe", nil, ColorAuto, false, false},
+		{"terminal", nil, ColorAuto, true, true},
+		{"always", nil, ColorAlways, false, true},
+		{"never", nil, ColorNever, true, false},
+		{"NO_COLOR", map[string]string{"NO_COLOR": "1"}, ColorAuto, true, false},
+		{"NO_COLOR always", map[string]string{"NO_COLOR": "1"}, ColorAlways, false, true},
+		{"FORCE_COLOR", map[string]string{"FORCE_COLOR": "1"}, ColorAuto, false, true},
+		{"FORCE_COLOR=0", map[string]string{"FORCE_COLOR": "0"}, ColorAuto, true, false},
+		{"CLICOLOR_FORCE", map[string]string{"CLICOLOR_FORCE": "1"}, ColorAuto, false, true},
+		{"CLICOLOR=0", map[string]string{"CLICOLOR": "0"}, ColorAuto, true, false},
+		{"NO_COLOR FORCE_COLOR", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, ColorAuto, true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			var w interface {
+				String() string
+				Write([]byte) (int, error)
+			} = new(bytes.Buffer)
+			if test.term {
+				w = new(fakeTerminal)
+			}
+			slog.New(NewTextHandler(WithWriter(w), WithColorMode(test.mode))).Info("message")
+			if got := strings.Contains(w.String(), "\x1b["); got != test.want {
+				t.Errorf("got colored %t, want %t: %q", got, test.want, w.String())
+			}
+		})
+	}
+}
+
+func TestForceColorProfile(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "2")
+	if h := NewTextHandler(WithWriter(new(bytes.Buffer))); h.profile != ProfileANSI256 {
+		t.Errorf("got profile %d, want %d", h.profile, ProfileANSI256)
+	}
+}
+
+func TestDumbTerminalProfile(t *testing.T) {
+	for _, key := range []string{"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "COLORTERM"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("TERM", "dumb")
+	if h := NewTextHandler(WithWriter(new(fakeTerminal))); h.profile != ProfileAscii {
+		t.Errorf("got profile %d of dumb terminal, want %d", h.profile, ProfileAscii)
+	}
+
+	// ColorAlways takes precedence over the environment, e.g. `| less -R`
+	var buf bytes.Buffer
+	h := NewTextHandler(WithWriter(&buf), WithColorMode(ColorAlways))
+	slog.New(h).Info("colored")
+	if seq := string(h.themes[ThemeInfo].sequence(h.isDark())); h.profile != ProfileANSI || !strings.Contains(buf.String(), seq+"INFO") {
+		t.Errorf("got profile %d and %q of ColorAlways, want %d and colors", h.profile, buf.String(), ProfileANSI)
+	}
+
+	t.Setenv("CLICOLOR_FORCE", "1")
+	if h := NewTextHandler(WithWriter(new(bytes.Buffer))); h.profile != ProfileANSI {
+		t.Errorf("got profile %d of forced dumb terminal, want %d", h.profile, ProfileANSI)
+	}
+}
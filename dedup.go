@@ -0,0 +1,230 @@
+package shandler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"log/slog"
+)
+
+// repeatedMark leads the count of the line collapsing the repeated records.
+const repeatedMark = "repeated ×"
+
+// dedup is the state of WithDedup shared by the clones of a handler,
+// as they write to the same output.
+type dedup struct {
+	window time.Duration
+	now    func() time.Time
+	cols   func(w io.Writer) int // the columns of the terminal, refer to terminalWidth
+
+	ttyOnce sync.Once
+	tty     bool
+
+	mu    sync.Mutex
+	key   []byte       // the last record written, refer to appendDedupKey
+	since time.Time    // when the last record was written
+	count int          // the repeats of the last record suppressed so far
+	last  *baseHandler // the handler and the record of the last repeat
+	level slog.Level
+	time  time.Time
+	timer *time.Timer // writes the pending line of the repeats once the window expires
+	runs  uint64      // counts the flushes, so an expired timer knows it's stale
+
+	// seq and rows of the line of the repeats written last on a terminal,
+	// it's updated in place only if the terminal is written nothing else since.
+	seq  uint64
+	rows int
+}
+
+// WithDedup collapses the consecutive identical records within window since the
+// first one is written, like syslog. The records are identical if they have the
+// same level, prefix, message and attrs, regardless of the time and caller.
+// Instead of the repeats a single line with the message "repeated ×N" of the N
+// repeats is written once the window expires or before the next different record,
+// call Flush to write it on exit. On a terminal the line is written by the first
+// repeat and updated in place by the others, unless something else was written
+// to the terminal after it by the handlers of this package.
+func WithDedup(window time.Duration) Option {
+	return func(cfg *baseHandler) {
+		cfg.dedup = &dedup{window: window, now: time.Now, cols: terminalWidth}
+	}
+}
+
+// Flush writes the line of the repeats of WithDedup if it's pending, e.g. before
+// the process exits. It's shared by the clones and does nothing without WithDedup.
+func (h *baseHandler) Flush() error {
+	if h.dedup == nil {
+		return nil
+	}
+	h.dedup.mu.Lock()
+	defer h.dedup.mu.Unlock()
+	return h.dedup.flush()
+}
+
+// handle writes r by h unless it repeats the last record.
+func (d *dedup) handle(h *baseHandler, r slog.Record) error {
+	key := NewBuffer()
+	defer key.Free()
+	appendDedupKey(key, h, r)
+	d.ttyOnce.Do(func() {
+		d.tty = h.isTTY()
+	})
+
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.key) > 0 && bytes.Equal(d.key, *key) && now.Sub(d.since) < d.window {
+		d.count++
+		d.last, d.level, d.time = h, r.Level, r.Time
+		if d.tty {
+			return d.writeRepeated()
+		}
+		if d.timer == nil {
+			run := d.runs
+			d.timer = time.AfterFunc(d.window-now.Sub(d.since), func() { d.expire(run) })
+		}
+		return nil
+	}
+
+	if err := d.flush(); err != nil {
+		return err
+	}
+	d.key = append(d.key[:0], *key...)
+	d.since = now
+	return h.render(r, h.write)
+}
+
+// expire writes the pending line of the repeats of run once its window expires.
+func (d *dedup) expire(run uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.runs == run {
+		// there's no caller to report the error of the writer to
+		_ = d.flush()
+	}
+}
+
+// flush ends the repeats of the last record and writes the line of them
+// if it's pending, d.mu must be locked.
+func (d *dedup) flush() error {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	var err error
+	if d.count > 0 && !d.tty {
+		err = d.writeRepeated()
+	}
+	d.key, d.count, d.last = d.key[:0], 0, nil
+	d.runs++
+	return err
+}
+
+// writeRepeated writes the line of the repeats, on a terminal it replaces the
+// previous line of the repeats if it's the last one written, d.mu must be locked.
+func (d *dedup) writeRepeated() error {
+	h := d.last.clone()
+	// the line is rendered without the attrs of WithAttrs
+	h.preformatted, h.groupPrefix, h.groups, h.nOpenGroups, h.encoderAttrs = nil, "", nil, 0, nil
+	r := slog.NewRecord(d.time, d.level, repeatedMark+strconv.Itoa(d.count), 0)
+	return h.render(r, func(buf *Buffer) error {
+		if d.tty && d.count > 1 && h.seq != nil && h.seq.Load() == d.seq {
+			// move the cursor to the start of the previous line and erase it to the end
+			line := NewBuffer()
+			defer line.Free()
+			line.Write(CSI)
+			line.WritePosInt(d.rows)
+			line.WriteByte('A')
+			line.Write(CSI)
+			line.WriteByte('J')
+			line.Write(*buf)
+			buf = line
+		}
+		seq, err := h.writeSeq(buf)
+		d.seq, d.rows = seq, wrappedRows(bytes.TrimSuffix(*buf, []byte{'\n'}), d.cols(h.w))
+		return err
+	})
+}
+
+// wrappedRows returns the rows of line on a terminal of cols columns,
+// it's 1 if cols is unknown.
+func wrappedRows(line []byte, cols int) int {
+	width := visibleWidth(line)
+	if cols <= 0 || width <= cols {
+		return 1
+	}
+	return (width + cols - 1) / cols
+}
+
+// appendDedupKey appends what identifies r handled by h to buf without rendering it,
+// i.e. the level, prefix, message and the attrs of r and WithAttrs.
+func appendDedupKey(buf *Buffer, h *baseHandler, r slog.Record) {
+	*buf = strconv.AppendInt(*buf, int64(r.Level), 10)
+	appendDedupString(buf, h.prefix)
+	appendDedupString(buf, r.Message)
+	buf.WriteByte(';')
+	buf.WritePosInt(len(h.preformatted))
+	buf.WriteByte(':')
+	buf.Write(h.preformatted)
+	for _, group := range h.groups {
+		appendDedupString(buf, group)
+	}
+	for _, attrs := range h.encoderAttrs {
+		buf.WriteByte('{')
+		for _, a := range attrs {
+			appendDedupAttr(buf, a)
+		}
+	}
+	buf.WriteByte('{')
+	r.Attrs(func(a slog.Attr) bool {
+		appendDedupAttr(buf, a)
+		return true
+	})
+}
+
+// appendDedupString appends s led by its length, so the strings are told apart.
+func appendDedupString(buf *Buffer, s string) {
+	buf.WriteByte(';')
+	buf.WritePosInt(len(s))
+	buf.WriteByte(':')
+	buf.WriteString(s)
+}
+
+// appendDedupAttr appends the key, kind and value of a, the values of the basic
+// kinds are appended without allocating, the others by their string.
+func appendDedupAttr(buf *Buffer, a slog.Attr) {
+	appendDedupString(buf, a.Key)
+	v := a.Value.Resolve()
+	buf.WriteByte(';')
+	buf.WritePosInt(int(v.Kind()))
+	buf.WriteByte('=')
+	switch v.Kind() {
+	case slog.KindString:
+		appendDedupString(buf, v.String())
+	case slog.KindInt64:
+		*buf = strconv.AppendInt(*buf, v.Int64(), 10)
+	case slog.KindUint64:
+		*buf = strconv.AppendUint(*buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		*buf = strconv.AppendUint(*buf, math.Float64bits(v.Float64()), 16)
+	case slog.KindBool:
+		*buf = strconv.AppendBool(*buf, v.Bool())
+	case slog.KindDuration:
+		*buf = strconv.AppendInt(*buf, int64(v.Duration()), 10)
+	case slog.KindTime:
+		*buf = v.Time().AppendFormat(*buf, time.RFC3339Nano)
+	case slog.KindGroup:
+		attrs := v.Group()
+		buf.WritePosInt(len(attrs))
+		for _, a := range attrs {
+			appendDedupAttr(buf, a)
+		}
+	default:
+		appendDedupString(buf, fmt.Sprint(v.Any()))
+	}
+}
@@ -0,0 +1,131 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+)
+
+func newDedupLogger(w io.Writer) (*slog.Logger, *time.Time) {
+	h := NewTextHandler(WithWriter(w), WithTimeFormat("-"), WithColorMode(ColorNever), WithDedup(time.Minute))
+	clock := time.Now()
+	h.dedup.now = func() time.Time { return clock }
+	h.dedup.cols = func(io.Writer) int { return 0 }
+	return slog.New(h), &clock
+}
+
+func TestDedup(t *testing.T) {
+	var buf bytes.Buffer
+	logger, clock := newDedupLogger(&buf)
+	db := logger.WithGroup("db").With("host", "a")
+	for i := 0; i < 4; i++ {
+		// the attrs of the clones are compared by what they render
+		logger.WithGroup("db").With("host", "a").Error("down", "err", "refused")
+	}
+	db.Error("down", "err", "timeout")
+	*clock = clock.Add(time.Minute)
+	db.Error("down", "err", "timeout")
+	db.Error("down", "err", "timeout")
+	if err := logger.Handler().(*TextHandler).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"- ERRO down db.host=a db.err=refused",
+		"- ERRO \"repeated ×3\"",
+		"- ERRO down db.host=a db.err=timeout",
+		"- ERRO down db.host=a db.err=timeout",
+		"- ERRO \"repeated ×1\"",
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDedupTerminal(t *testing.T) {
+	var term fakeTerminal
+	logger, _ := newDedupLogger(&term)
+	for i := 0; i < 3; i++ {
+		logger.Info("flap")
+	}
+	logger.Info("ok")
+
+	want := "- INFO flap\n- INFO \"repeated ×1\"\n\x1b[1A\x1b[J- INFO \"repeated ×2\"\n- INFO ok\n"
+	if got := term.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// another handler writes to the terminal between the repeats
+	term.Reset()
+	other := slog.New(NewTextHandler(WithWriter(&term), WithTimeFormat("-"), WithColorMode(ColorNever)))
+	for i := 0; i < 3; i++ {
+		logger.Info("flap")
+		other.Info("other")
+	}
+	want = "- INFO flap\n- INFO other\n- INFO \"repeated ×1\"\n- INFO other\n- INFO \"repeated ×2\"\n- INFO other\n"
+	if got := term.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// the wrapped line of the repeats is erased by all its rows
+	term.Reset()
+	logger, _ = newDedupLogger(&term)
+	logger.Handler().(*TextHandler).dedup.cols = func(io.Writer) int { return 8 }
+	for i := 0; i < 3; i++ {
+		logger.Info("flap")
+	}
+	want = "- INFO flap\n- INFO \"repeated ×1\"\n\x1b[3A\x1b[J- INFO \"repeated ×2\"\n"
+	if got := term.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDedupExpiry(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewTextHandler(WithWriter(&buf), WithTimeFormat("-"), WithColorMode(ColorNever), WithDedup(10*time.Millisecond))
+	logger := slog.New(h)
+	for i := 0; i < 3; i++ {
+		logger.Warn("flap")
+	}
+
+	want := "- WARN flap\n- WARN \"repeated ×2\"\n"
+	deadline := time.Now().Add(time.Second)
+	for {
+		// the timer writes under the lock of dedup
+		h.dedup.mu.Lock()
+		got := buf.String()
+		h.dedup.mu.Unlock()
+		if got == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestDedupAllocs checks the records are compared without rendering them.
+func TestDedupAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+	record.AddAttrs(slog.Int("n", 1), slog.String("s", "x"))
+	allocs := func(h *TextHandler) float64 {
+		return testing.AllocsPerRun(100, func() {
+			_ = h.Handle(context.Background(), record)
+		})
+	}
+	h := NewTextHandler(WithWriter(io.Discard), WithDedup(time.Hour))
+	_ = h.Handle(context.Background(), record)
+	if got := allocs(h); got > 0 {
+		t.Errorf("got %v allocs per repeat, want 0", got)
+	}
+}
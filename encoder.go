@@ -0,0 +1,246 @@
+package shandler
+
+import (
+	"slices"
+
+	"log/slog"
+)
+
+// Encoder renders the records of a custom format, it's wrapped in the handler
+// machinery by NewEncoderHandler which takes care of the level filtering,
+// themes, prefix, groups and Replacer. Encode may be called concurrently.
+type Encoder interface {
+	// Encode appends e to buf, the line break included, e must not be retained.
+	// The record is dropped if there's an error, which is returned by Handle.
+	Encode(buf *Buffer, e *Entry) error
+}
+
+// EncoderFunc is an Encoder of a function.
+type EncoderFunc func(buf *Buffer, e *Entry) error
+
+func (f EncoderFunc) Encode(buf *Buffer, e *Entry) error {
+	return f(buf, e)
+}
+
+// Entry is a record prepared for the Encoder. The built-in attributes have been
+// passed to the Replacer, their Key is empty if they're absent or discarded.
+// The time is absent if it's zero and the source is absent unless WithCaller,
+// its value is a *slog.Source if it's not replaced.
+type Entry struct {
+	Time    slog.Attr
+	Level   slog.Attr
+	Source  slog.Attr
+	Message slog.Attr
+	Prefix  string
+
+	// Attrs are the attrs of WithAttrs and the record nested in the groups of
+	// WithGroup. They're resolved and replaced, the empty attrs and groups
+	// are removed and the groups without key are inlined.
+	Attrs []slog.Attr
+
+	b     *baseBuilder
+	level levelDef
+}
+
+// TimeFormat returns the format of WithTimeFormat.
+func (e *Entry) TimeFormat() string {
+	return e.b.h.timeFormat
+}
+
+// LevelLabel returns the level rendered by the LevelFormatter, it's the string
+// of the value if the Replacer changes it to another type.
+func (e *Entry) LevelLabel() string {
+	if l, ok := e.Level.Value.Any().(slog.Level); ok {
+		return e.b.h.formatLevel(l, e.level)
+	}
+	return e.Level.Value.String()
+}
+
+// Caller returns the source as function:line like the text handler,
+// the function is full with WithFullCaller.
+func (e *Entry) Caller() string {
+	if src, ok := e.Source.Value.Any().(*slog.Source); ok {
+		return e.b.caller(*src)
+	}
+	return e.Source.Value.String()
+}
+
+// Theme returns the theme of schema, it's nil if the output isn't colored.
+func (e *Entry) Theme(schema ThemeSchema) *Theme {
+	if !e.b.h.colored {
+		return nil
+	}
+	return e.b.h.themes[schema]
+}
+
+// LevelTheme returns the theme of the level, it's nil if the output isn't colored.
+func (e *Entry) LevelTheme() *Theme {
+	if !e.b.h.colored {
+		return nil
+	}
+	return e.b.h.levelTheme(e.level)
+}
+
+// WriteThemed writes s rendered with theme, it's plain if theme is nil.
+func (e *Entry) WriteThemed(buf *Buffer, theme *Theme, s string) {
+	e.b.h.writeTheme(theme, buf, s)
+}
+
+// EncoderHandler is the handler of an Encoder.
+type EncoderHandler struct {
+	*baseHandler
+}
+
+// NewEncoderHandler creates a handler rendering the records by enc,
+// the options of the layout like WithLayout are ignored.
+func NewEncoderHandler(enc Encoder, opts ...Option) *EncoderHandler {
+	h := createHandler(false, opts...)
+	h.encoder = enc
+	return &EncoderHandler{h}
+}
+
+func (e *EncoderHandler) WithPrefix(prefix string) slog.Handler {
+	return &EncoderHandler{e.withPrefix(prefix)}
+}
+
+func (e *EncoderHandler) WithThemes(themes Themes) slog.Handler {
+	return &EncoderHandler{e.withThemes(themes)}
+}
+
+func (e *EncoderHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &EncoderHandler{e.withAttrs(attrs)}
+}
+
+func (e *EncoderHandler) WithGroup(name string) slog.Handler {
+	return &EncoderHandler{e.withGroup(name)}
+}
+
+type encoderBuilder struct {
+	*baseBuilder
+	entry Entry
+	err   error
+}
+
+func (h *baseHandler) createEncoderBuilder(buf *Buffer, r slog.Record) *encoderBuilder {
+	b := &encoderBuilder{baseBuilder: h.createBaseBuilder(buf, r)}
+	b.entry.b = b.baseBuilder
+	b.entry.level = h.findLevel(r.Level)
+	return b
+}
+
+func (b *encoderBuilder) start() {}
+func (b *encoderBuilder) close() {}
+
+func (b *encoderBuilder) appendTime() {
+	if b.r.Time.IsZero() {
+		return
+	}
+	if a, ok := b.replaceBuiltin(slog.Time(slog.TimeKey, b.r.Time)); ok {
+		b.entry.Time = a
+	}
+}
+
+func (b *encoderBuilder) appendLevel() {
+	a, ok := b.replaceBuiltin(slog.Any(slog.LevelKey, b.r.Level))
+	if !ok {
+		return
+	}
+	if l, isLevel := a.Value.Any().(slog.Level); isLevel {
+		b.entry.level = b.h.findLevel(l)
+	}
+	b.entry.Level = a
+}
+
+func (b *encoderBuilder) appendCaller() {
+	if !b.h.caller || b.r.PC <= 0 {
+		return
+	}
+	src := b.source()
+	if a, ok := b.replaceBuiltin(slog.Any(slog.SourceKey, &src)); ok {
+		b.entry.Source = a
+	}
+}
+
+func (b *encoderBuilder) appendPrefix() {
+	b.entry.Prefix = b.h.prefix
+}
+
+func (b *encoderBuilder) appendMessage() {
+	if a, ok := b.replaceBuiltin(slog.String(slog.MessageKey, b.r.Message)); ok {
+		b.entry.Message = a
+	}
+}
+
+// appendAttrs nests the attrs of the record in the groups
+// along with the ones of the handler at each depth.
+func (b *encoderBuilder) appendAttrs() {
+	attrs := make([]slog.Attr, 0, b.r.NumAttrs())
+	b.r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	attrs = b.normalize(attrs, b.h.groups)
+	for depth := len(b.h.groups); depth >= 0; depth-- {
+		if depth < len(b.h.encoderAttrs) && len(b.h.encoderAttrs[depth]) > 0 {
+			attrs = append(slices.Clip(b.h.encoderAttrs[depth]), attrs...)
+		}
+		if depth > 0 && len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: b.h.groups[depth-1], Value: slog.GroupValue(attrs...)}}
+		}
+	}
+	b.entry.Attrs = attrs
+}
+
+// preformat keeps the normalized attrs at the depth of the current group.
+func (b *encoderBuilder) preformat(attrs []slog.Attr) {
+	defer b.freeGroups()
+	depth := len(b.h.groups)
+	encoderAttrs := slices.Clone(b.h.encoderAttrs)
+	if len(encoderAttrs) <= depth {
+		encoderAttrs = append(encoderAttrs, make([][]slog.Attr, depth+1-len(encoderAttrs))...)
+	}
+	encoderAttrs[depth] = append(slices.Clip(encoderAttrs[depth]), b.normalize(attrs, b.h.groups)...)
+	b.h.encoderAttrs = encoderAttrs
+}
+
+// normalize resolves and replaces attrs under groups, the empty attrs
+// and groups are removed and the groups without key are inlined.
+func (b *encoderBuilder) normalize(attrs []slog.Attr, groups []string) []slog.Attr {
+	normalized := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			sub := a.Value.Group()
+			if a.Key != "" {
+				sub = b.normalize(sub, append(slices.Clip(groups), a.Key))
+			} else {
+				sub = b.normalize(sub, groups)
+			}
+			if len(sub) == 0 {
+				continue
+			}
+			if a.Key == "" {
+				normalized = append(normalized, sub...)
+			} else {
+				normalized = append(normalized, slog.Attr{Key: a.Key, Value: slog.GroupValue(sub...)})
+			}
+			continue
+		}
+
+		if b.h.replacer != nil {
+			if a = b.h.replacer(groups, a); a.Key == "" {
+				continue
+			}
+			a.Value = a.Value.Resolve()
+		}
+		if !a.Equal(slog.Attr{}) {
+			normalized = append(normalized, a)
+		}
+	}
+	return normalized
+}
+
+func (b *encoderBuilder) output() *Buffer {
+	b.err = b.h.encoder.Encode(b.buf, &b.entry)
+	return b.buf
+}
@@ -0,0 +1,128 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"log/slog"
+)
+
+// mapEncoder encodes the entries as JSON objects by maps.
+var mapEncoder = EncoderFunc(func(buf *Buffer, e *Entry) error {
+	m := attrsMap(e.Attrs)
+	for _, a := range []slog.Attr{e.Time, e.Level, e.Message} {
+		if a.Key != "" {
+			m[a.Key] = a.Value.Any()
+		}
+	}
+	if e.Level.Key != "" {
+		m[e.Level.Key] = e.LevelLabel()
+	}
+	if e.Source.Key != "" {
+		m[e.Source.Key] = e.Caller()
+	}
+	if e.Prefix != "" {
+		m[prefixKey] = e.Prefix
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	buf.WriteByte('\n')
+	return nil
+})
+
+func attrsMap(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			m[a.Key] = attrsMap(a.Value.Group())
+		} else {
+			m[a.Key] = a.Value.Any()
+		}
+	}
+	return m
+}
+
+func TestEncoderHandlerConformance(t *testing.T) {
+	for name, opts := range conformanceOptions {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := NewEncoderHandler(mapEncoder, append(opts, WithWriter(&buf))...)
+			results := func() []map[string]any {
+				var ms []map[string]any
+				for _, line := range bytes.Split(buf.Bytes(), []byte{'\n'}) {
+					if len(line) == 0 {
+						continue
+					}
+					var m map[string]any
+					if err := json.Unmarshal(line, &m); err != nil {
+						t.Fatalf("%s: %q", err, line)
+					}
+					ms = append(ms, m)
+				}
+				return ms
+			}
+			if err := slogtest.TestHandler(h, results); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestEncoderHandler(t *testing.T) {
+	var buf bytes.Buffer
+	replacer := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "secret" {
+			return slog.String(a.Key, strings.Join(groups, ".")+":***")
+		}
+		return a
+	}
+	var h slog.Handler = NewEncoderHandler(mapEncoder,
+		WithWriter(&buf),
+		WithReplacer(replacer),
+		WithCustomLevel(LevelFatal, "FATL", nil),
+	)
+	h = h.WithAttrs([]slog.Attr{slog.Int("a", 1)}).WithGroup("g").
+		WithAttrs([]slog.Attr{slog.String("secret", "x")}).(Handler).WithPrefix("db")
+	slog.New(h).Log(context.Background(), LevelFatal, "msg", "b", 2)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"a":      float64(1),
+		"g":      map[string]any{"secret": "g:***", "b": float64(2)},
+		"level":  "FATL",
+		"msg":    "msg",
+		"prefix": "db",
+	}
+	delete(got, slog.TimeKey)
+	if g, w := mustJSON(t, got), mustJSON(t, want); g != w {
+		t.Errorf("got %s, want %s", g, w)
+	}
+
+	// the error of the encoder is returned and nothing is written
+	buf.Reset()
+	failed := errors.New("failed")
+	h = NewEncoderHandler(EncoderFunc(func(*Buffer, *Entry) error { return failed }), WithWriter(&buf))
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "", 0)); err != failed || buf.Len() > 0 {
+		t.Errorf("got %v and %q, want %v", err, buf.String(), failed)
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
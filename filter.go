@@ -0,0 +1,209 @@
+package shandler
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"log/slog"
+)
+
+const (
+	// DirectivesEnv is the environment variable read by WithDirectivesEnv by default.
+	DirectivesEnv = "SHANDLER_LOG"
+
+	// LevelOff disables every record when used as the level of a directive.
+	LevelOff slog.Level = math.MaxInt32
+
+	// levelAll enables every record, it's used by the directives without level.
+	levelAll slog.Level = math.MinInt32
+
+	directiveSep      = ','
+	directiveLevelSep = '='
+	prefixSep         = '.'
+)
+
+// LevelFilter sets the level per prefix by RUST_LOG-style directives:
+//
+//	info,db=debug,http.client=warn
+//
+// The directive without prefix is the default level of the prefixes without
+// directive, if it's absent, the level of the handler is used. A directive
+// matches the prefix and the nested prefixes separated by '.', the most
+// specific one wins, so "http.client.pool" is filtered by "http.client=warn".
+// A prefix without level like "db" enables every level of it.
+//
+// The levels are named as slog.Level.UnmarshalText, e.g. "debug", "INFO+2",
+// along with "trace", "notice", "fatal" and "off".
+//
+// A LevelFilter is safe for concurrent use, it can be changed by Set at runtime
+// and the change applies to every handler created with it.
+type LevelFilter struct {
+	set atomic.Pointer[directiveSet]
+}
+
+type directiveSet struct {
+	directives string
+	fallback   *slog.Level
+	prefixes   map[string]slog.Level
+}
+
+// NewLevelFilter returns a LevelFilter parsed from directives.
+func NewLevelFilter(directives string) (*LevelFilter, error) {
+	f := new(LevelFilter)
+	if err := f.Set(directives); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Set replaces the directives of f, they're left unchanged if there's an error.
+func (f *LevelFilter) Set(directives string) error {
+	set, err := parseDirectives(directives, true)
+	if err != nil {
+		return err
+	}
+	f.set.Store(set)
+	return nil
+}
+
+// String returns the directives of f.
+func (f *LevelFilter) String() string {
+	if set := f.set.Load(); set != nil {
+		return set.directives
+	}
+	return ""
+}
+
+// Level returns the level of prefix and whether there's a directive for it.
+func (f *LevelFilter) Level(prefix string) (slog.Level, bool) {
+	set := f.set.Load()
+	if set == nil {
+		return 0, false
+	}
+	for p := prefix; p != ""; {
+		if level, ok := set.prefixes[p]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(p, prefixSep)
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	if set.fallback != nil {
+		return *set.fallback, true
+	}
+	return 0, false
+}
+
+// parseDirectives parses the directives, the invalid ones
+// are skipped unless strict is true.
+func parseDirectives(directives string, strict bool) (*directiveSet, error) {
+	set := &directiveSet{prefixes: make(map[string]slog.Level)}
+	var valid []string
+	for _, directive := range strings.Split(directives, string(directiveSep)) {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+
+		prefix, name, found := strings.Cut(directive, string(directiveLevelSep))
+		prefix, name = strings.TrimSpace(prefix), strings.TrimSpace(name)
+		var level slog.Level
+		var err error
+		switch {
+		case !found:
+			// a single word is either the default level or a prefix enabling every level
+			if level, err = parseLevel(prefix); err == nil {
+				prefix = ""
+			} else {
+				level, err = levelAll, nil
+			}
+		case prefix == "":
+			err = fmt.Errorf("shandler: directive %q: missing prefix", directive)
+		default:
+			level, err = parseLevel(name)
+		}
+		if err != nil {
+			if strict {
+				return nil, err
+			}
+			continue
+		}
+
+		if prefix == "" {
+			set.fallback = &level
+		} else {
+			set.prefixes[prefix] = level
+		}
+		valid = append(valid, directive)
+	}
+	set.directives = strings.Join(valid, string(directiveSep))
+	return set, nil
+}
+
+// parseLevel parses the level name used in directives.
+func parseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "trace":
+		return LevelTrace, nil
+	case "notice":
+		return LevelNotice, nil
+	case "fatal":
+		return LevelFatal, nil
+	case "off":
+		return LevelOff, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf("shandler: %w", err)
+	}
+	return level, nil
+}
+
+// minLevel returns the level used by Enabled, it's the level of the handler's
+// prefix if there's a directive for it.
+func (h *baseHandler) minLevel() slog.Level {
+	if h.filter != nil {
+		if level, ok := h.filter.Level(h.prefix); ok {
+			return level
+		}
+	}
+	return h.level.Level()
+}
+
+// WithLevelFilter filters the records by the level of the handler's prefix,
+// refer to LevelFilter.
+func WithLevelFilter(filter *LevelFilter) Option {
+	return func(cfg *baseHandler) {
+		cfg.filter = filter
+	}
+}
+
+// WithDirectives filters the records by directives, the invalid ones are
+// skipped. Use NewLevelFilter and WithLevelFilter to check the directives
+// or change them at runtime.
+func WithDirectives(directives string) Option {
+	return func(cfg *baseHandler) {
+		set, _ := parseDirectives(directives, false)
+		cfg.filter = new(LevelFilter)
+		cfg.filter.set.Store(set)
+	}
+}
+
+// WithDirectivesEnv is like WithDirectives with the directives read from
+// the environment variable name, DirectivesEnv is used if name is empty.
+// It does nothing if the variable is unset.
+func WithDirectivesEnv(name string) Option {
+	if name == "" {
+		name = DirectivesEnv
+	}
+	return func(cfg *baseHandler) {
+		if directives, ok := os.LookupEnv(name); ok {
+			WithDirectives(directives)(cfg)
+		}
+	}
+}
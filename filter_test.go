@@ -0,0 +1,63 @@
+package shandler
+
+import (
+	"context"
+	"testing"
+
+	"log/slog"
+)
+
+func TestLevelFilter(t *testing.T) {
+	filter, err := NewLevelFilter("warn, db=debug ,http.client=error,cache,metrics=off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := NewTextHandler(WithLevelFilter(filter))
+
+	tests := []struct {
+		prefix string
+		level  slog.Level
+		want   bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"", slog.LevelWarn, true},
+		{"db", slog.LevelDebug, true},
+		{"db.pool", slog.LevelDebug, true},
+		{"dbx", slog.LevelDebug, false},
+		{"http", slog.LevelWarn, true},
+		{"http.client", slog.LevelWarn, false},
+		{"http.client.pool", slog.LevelError, true},
+		{"cache", LevelTrace, true},
+		{"metrics", LevelFatal, false},
+	}
+	for _, test := range tests {
+		h := root.WithPrefix(test.prefix)
+		if got := h.Enabled(context.Background(), test.level); got != test.want {
+			t.Errorf("%q %s: got %t, want %t", test.prefix, test.level, got, test.want)
+		}
+	}
+
+	if err := filter.Set("db=verbose"); err == nil {
+		t.Errorf("expected error of the invalid level")
+	}
+	if err := filter.Set("db=error"); err != nil {
+		t.Fatal(err)
+	}
+	if h := root.WithPrefix("db"); h.Enabled(context.Background(), slog.LevelWarn) {
+		t.Errorf("the change of directives isn't applied")
+	}
+	if h := root.WithPrefix("http"); !h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Errorf("expected the level of handler without default directive")
+	}
+}
+
+func TestDirectivesEnv(t *testing.T) {
+	t.Setenv(DirectivesEnv, "error,=debug,db=debug,http=loud")
+	h := NewTextHandler(WithDirectivesEnv(""))
+	if got, want := h.filter.String(), "error,db=debug"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !h.WithPrefix("db").Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("expected debug enabled for db")
+	}
+}
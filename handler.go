@@ -3,10 +3,13 @@ package shandler
 import (
 	"context"
 	"io"
+	"maps"
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/mattn/go-isatty"
-	"golang.org/x/exp/slices"
 	"log/slog"
 )
 
@@ -62,11 +65,25 @@ type baseHandler struct {
 	// w is output writer, default using os.Stderr
 	w io.Writer
 
-	// tty only tty can be colored output
-	tty bool
+	// colored whether the output is colored, refer to ColorMode
+	colored   bool
+	colorMode ColorMode
 
-	// level is logger min Level, default is slog.LevelInfo
-	level slog.Level
+	// profile the color profile of the output, it's detected if ProfileAuto
+	profile ColorProfile
+
+	// background of the output, it's queried if BackgroundAuto
+	background BackgroundMode
+
+	// queried the background queried from the terminal, refer to isDark
+	queried *backgroundQuery
+
+	// level reports the logger min Level, default is slog.LevelInfo.
+	// It's shared by all the clones, so a *slog.LevelVar changes them together.
+	level slog.Leveler
+
+	// filter overrides level by the prefix, refer to LevelFilter
+	filter *LevelFilter
 
 	// prefix output prefix in every record
 	prefix string
@@ -81,15 +98,85 @@ type baseHandler struct {
 	fullCaller bool
 
 	themes Themes
+
+	// preset the name of the preset fills the themes, refer to WithPreset
+	preset string
+
+	// levels sorted definitions of how levels are rendered, refer to WithCustomLevel
+	levels []levelDef
+
+	// levelFormatter renders the level of the text handler, refer to LevelFormatter
+	levelFormatter LevelFormatter
+
+	// levelBadge renders the level of the text handler as a badge
+	levelBadge bool
+
+	// rules styles the attr values of the text handler, refer to StyleRule
+	rules []StyleRule
+
+	// encoder renders the records instead of the text or json builder, refer to Encoder
+	encoder Encoder
+
+	// encoderAttrs the normalized attrs of WithAttrs for the encoder by the depth of groups
+	encoderAttrs [][]slog.Attr
+
+	// layout orders the components of the text handler, refer to Layout
+	layout *Layout
+
+	// hash colors the prefix and the values of chosen keys, refer to WithHashedPrefix
+	hash *hashColors
+
+	// prefixStyle the hashed theme of the prefix, refer to prefixTheme
+	prefixStyle *Theme
+
+	// dedup collapses the consecutive identical records, refer to WithDedup
+	dedup *dedup
+
+	// seq counts the writes to the terminal w by all the handlers, refer to writeSeqOf
+	seq *atomic.Uint64
 }
 
 func (h *baseHandler) isTTY() bool {
-	if f, ok := h.w.(File); ok {
-		return isatty.IsTerminal(f.Fd())
+	return isTerminal(h.w)
+}
+
+// isTerminal reports whether w is a terminal, refer to Terminal.
+func isTerminal(w io.Writer) bool {
+	switch w := w.(type) {
+	case Terminal:
+		return w.IsTerminal()
+	case interface{ Fd() uintptr }:
+		return isatty.IsTerminal(w.Fd())
 	}
 	return false
 }
 
+// writeSeqs the counters of writeSeqOf keyed by the file descriptors or the Terminals.
+var writeSeqs sync.Map
+
+// writeSeqOf returns the counter of the writes to the terminal w shared by
+// all the handlers writing to it, so a handler can tell whether the last
+// line of the terminal is its own. It's nil if w isn't a terminal.
+func writeSeqOf(w io.Writer) *atomic.Uint64 {
+	var key any
+	switch w := w.(type) {
+	case Terminal:
+		if !w.IsTerminal() || !reflect.TypeOf(w).Comparable() {
+			return nil
+		}
+		key = w
+	case interface{ Fd() uintptr }:
+		if !isatty.IsTerminal(w.Fd()) {
+			return nil
+		}
+		key = w.Fd()
+	default:
+		return nil
+	}
+	seq, _ := writeSeqs.LoadOrStore(key, new(atomic.Uint64))
+	return seq.(*atomic.Uint64)
+}
+
 // Enabled reports whether the handler handles records at the given level.
 // The handler ignores records whose level is lower.
 // It is called early, before any arguments are processed,
@@ -100,7 +187,7 @@ func (h *baseHandler) isTTY() bool {
 // The context is passed so Enabled can use its values
 // to make a decision.
 func (h *baseHandler) Enabled(_ context.Context, level slog.Level) bool {
-	return level >= h.level
+	return level >= h.minLevel()
 }
 
 // Handle handles the Record.
@@ -121,31 +208,72 @@ func (h *baseHandler) Enabled(_ context.Context, level slog.Level) bool {
 //   - If a group has no Attrs (even if it has a non-empty key),
 //     ignore it.
 func (h *baseHandler) Handle(_ context.Context, r slog.Record) error {
+	if h.dedup != nil {
+		return h.dedup.handle(h, r)
+	}
+	return h.render(r, h.write)
+}
+
+// render builds r and passes the output to fn, the output is released after fn.
+func (h *baseHandler) render(r slog.Record, fn func(buf *Buffer) error) error {
 	b := h.createBuilder(NewBuffer(), r)
 	defer b.free()
 	b.start()
-	b.appendTime()
-	b.appendLevel()
-	b.appendCaller()
-	b.appendPrefix()
-	b.appendMessage()
-	b.appendAttrs()
+	if tb, ok := b.(*textBuilder); ok && h.layout != nil {
+		tb.appendLayout(h.layout)
+	} else {
+		b.appendTime()
+		b.appendLevel()
+		b.appendCaller()
+		b.appendPrefix()
+		b.appendMessage()
+		b.appendAttrs()
+	}
 	b.close()
 	buf := b.output()
+	if eb, ok := b.(*encoderBuilder); ok && eb.err != nil {
+		return eb.err
+	}
+	return fn(buf)
+}
+
+func (h *baseHandler) write(buf *Buffer) error {
+	_, err := h.writeSeq(buf)
+	return err
+}
+
+// writeSeq writes buf and returns the count of the writes to the terminal
+// including it, refer to writeSeqOf. It's 0 if the output isn't a terminal.
+func (h *baseHandler) writeSeq(buf *Buffer) (uint64, error) {
 	h.mux.Lock()
 	defer h.mux.Unlock()
 	_, err := h.w.Write(*buf)
-	return err
+	if h.seq == nil {
+		return 0, err
+	}
+	return h.seq.Add(1), err
 }
 
-// WithAttrs returns a new Handler whose attributes consist of
+// withAttrs returns a new Handler whose attributes consist of
 // both the receiver's attributes and the arguments.
 // The Handler owns the slice: it may retain, modify or discard it.
-func (h *baseHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return nil
+//
+// The attributes are formatted once into preformatted, so they
+// don't need to be rendered again for every record.
+func (h *baseHandler) withAttrs(attrs []slog.Attr) *baseHandler {
+	// Empty groups are ignored, so if the entire slice consists of
+	// them, there is nothing to do.
+	if countEmptyGroups(attrs) == len(attrs) {
+		return h
+	}
+
+	h2 := h.clone()
+	b := h2.createBuilder((*Buffer)(&h2.preformatted), slog.Record{})
+	b.preformat(attrs)
+	return h2
 }
 
-// WithGroup returns a new Handler with the given group appended to
+// withGroup returns a new Handler with the given group appended to
 // the receiver's existing groups.
 // The keys of all subsequent attributes, whether added by With or in a
 // Record, should be qualified by the sequence of group names.
@@ -163,21 +291,20 @@ func (h *baseHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 //
 //	logger.LogAttrs(level, msg, slog.Group("s", slog.Int("a", 1), slog.Int("b", 2)))
 //
-// If the name is empty, WithGroup returns the receiver.
-func (h *baseHandler) WithGroup(name string) slog.Handler {
+// If the name is empty, withGroup returns the receiver.
+func (h *baseHandler) withGroup(name string) *baseHandler {
 	if name == "" {
 		return h
 	}
 	h2 := h.clone()
-	h2.initThemes()
 	h2.groups = append(h2.groups, name)
 	return h2
 }
 
 func (h *baseHandler) withPrefix(prefix string) *baseHandler {
 	h2 := h.clone()
-	h2.initThemes()
 	h2.prefix = prefix
+	h2.updatePrefixTheme()
 	return h2
 }
 
@@ -190,27 +317,59 @@ func (h *baseHandler) withThemes(themes Themes) *baseHandler {
 	return h2
 }
 
+// countEmptyGroups returns the number of empty group values in its argument.
+func countEmptyGroups(as []slog.Attr) int {
+	n := 0
+	for _, a := range as {
+		if a.Value.Kind() == slog.KindGroup && len(a.Value.Group()) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
 func (h *baseHandler) createBuilder(buf *Buffer, r slog.Record) Builder {
+	if h.encoder != nil {
+		return h.createEncoderBuilder(buf, r)
+	}
 	if h.json {
-		return nil
+		return h.createJsonBuilder(buf, r)
 	}
-	return &textBuilder{h.createBaseBuilder(buf, r)}
+	return &textBuilder{baseBuilder: h.createBaseBuilder(buf, r)}
 }
 
 func (h *baseHandler) clone() *baseHandler {
 	return &baseHandler{
-		preformatted: slices.Clip(h.preformatted),
-		groupPrefix:  h.groupPrefix,
-		groups:       slices.Clip(h.groups),
-		nOpenGroups:  h.nOpenGroups,
-		json:         h.json,
-		timeFormat:   h.timeFormat,
-		w:            h.w,
-		level:        h.level,
-		prefix:       h.prefix,
-		replacer:     h.replacer,
-		caller:       h.caller,
-		fullCaller:   h.fullCaller,
-		themes:       h.themes,
+		preformatted:   slices.Clip(h.preformatted),
+		groupPrefix:    h.groupPrefix,
+		groups:         slices.Clip(h.groups),
+		nOpenGroups:    h.nOpenGroups,
+		json:           h.json,
+		timeFormat:     h.timeFormat,
+		w:              h.w,
+		colored:        h.colored,
+		colorMode:      h.colorMode,
+		level:          h.level,
+		filter:         h.filter,
+		prefix:         h.prefix,
+		replacer:       h.replacer,
+		caller:         h.caller,
+		fullCaller:     h.fullCaller,
+		profile:        h.profile,
+		background:     h.background,
+		queried:        h.queried,
+		themes:         maps.Clone(h.themes),
+		preset:         h.preset,
+		levels:         h.levels,
+		levelFormatter: h.levelFormatter,
+		levelBadge:     h.levelBadge,
+		rules:          slices.Clip(h.rules),
+		encoder:        h.encoder,
+		encoderAttrs:   slices.Clip(h.encoderAttrs),
+		layout:         h.layout,
+		hash:           h.hash,
+		prefixStyle:    h.prefixStyle,
+		dedup:          h.dedup,
+		seq:            h.seq,
 	}
 }
@@ -0,0 +1,211 @@
+package shandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"log/slog"
+)
+
+var conformanceOptions = map[string][]Option{
+	"default": nil,
+	"caller":  {WithCaller(), WithPrefix("app")},
+	"full":    {WithFullCaller(), WithCaller(), WithTimeFormat(time.DateTime)},
+}
+
+func TestTextHandlerConformance(t *testing.T) {
+	for name, opts := range conformanceOptions {
+		t.Run(name, func(t *testing.T) {
+			testTextHandlerConformance(t, opts...)
+		})
+	}
+}
+
+func TestJsonHandlerConformance(t *testing.T) {
+	for name, opts := range conformanceOptions {
+		t.Run(name, func(t *testing.T) {
+			testJsonHandlerConformance(t, opts...)
+		})
+	}
+}
+
+func testTextHandlerConformance(t *testing.T, opts ...Option) {
+	var buf bytes.Buffer
+	h := NewTextHandler(append(opts, WithWriter(&buf))...)
+	results := func() []map[string]any {
+		var ms []map[string]any
+		for _, line := range bytes.Split(buf.Bytes(), []byte{'\n'}) {
+			if len(line) == 0 {
+				continue
+			}
+			m, err := parseTextLine(string(line), h.timeFormat)
+			if err != nil {
+				t.Fatalf("%s: %q", err, line)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	if err := slogtest.TestHandler(h, results); err != nil {
+		t.Fatal(err)
+	}
+
+	// the values and the message beyond the cases of slogtest
+	buf.Reset()
+	msg, value := `a b=c "d"`+"\ne", "x y=z\n"
+	slog.New(h).WithGroup("g").Info(msg, "k", value, "a b", "")
+	ms := results()
+	if len(ms) != 1 {
+		t.Fatalf("got %d records, want 1", len(ms))
+	}
+	g, _ := ms[0]["g"].(map[string]any)
+	if ms[0][slog.MessageKey] != msg || g == nil || g["k"] != value || g["a b"] != "" {
+		t.Errorf("got %v, want message %q and g.k %q", ms[0], msg, value)
+	}
+}
+
+func testJsonHandlerConformance(t *testing.T, opts ...Option) {
+	var buf bytes.Buffer
+	h := NewJsonHandler(append(opts, WithWriter(&buf))...)
+	results := func() []map[string]any {
+		var ms []map[string]any
+		for _, line := range bytes.Split(buf.Bytes(), []byte{'\n'}) {
+			if len(line) == 0 {
+				continue
+			}
+			var m map[string]any
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatalf("%s: %q", err, line)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+	if err := slogtest.TestHandler(h, results); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// parseTextLine parses a line written by the TextHandler back into a map:
+//
+//	[time] LEVEL [<caller>] [prefix]: message key=value group.key=value
+//
+// the dotted keys are nested into maps the same way as the JSON output.
+func parseTextLine(line, timeFormat string) (map[string]any, error) {
+	m := make(map[string]any)
+	fields, err := splitTextFields(line)
+	if err != nil {
+		return nil, err
+	}
+	if n := strings.Count(timeFormat, string(textAttrSep)) + 1; len(fields) >= n {
+		ts := strings.Join(fields[:n], string(textAttrSep))
+		if _, err := time.Parse(timeFormat, ts); err == nil {
+			m[slog.TimeKey] = ts
+			fields = fields[n:]
+		}
+	}
+	if len(fields) == 0 {
+		return nil, strconv.ErrSyntax
+	}
+	m[slog.LevelKey] = fields[0]
+	fields = fields[1:]
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "<") {
+		m[slog.SourceKey] = strings.Trim(fields[0], "<>")
+		fields = fields[1:]
+	}
+	// the prefix is absent if it's empty
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "[") && strings.HasSuffix(fields[0], "]:") {
+		m[prefixKey] = fields[0][1 : len(fields[0])-2]
+		fields = fields[1:]
+	}
+
+	// the message is a single field, it's quoted if it has spaces or '='
+	if len(fields) > 0 {
+		if msg, err := parseTextValue(fields[0]); err == nil {
+			m[slog.MessageKey] = msg
+			fields = fields[1:]
+		}
+	}
+
+	for _, field := range fields {
+		key, value, err := parseTextAttr(field)
+		if err != nil {
+			return nil, err
+		}
+		names := strings.Split(key, string(groupKeySep))
+		group := m
+		for _, name := range names[:len(names)-1] {
+			g, ok := group[name].(map[string]any)
+			if !ok {
+				g = make(map[string]any)
+				group[name] = g
+			}
+			group = g
+		}
+		group[names[len(names)-1]] = value
+	}
+	return m, nil
+}
+
+// parseTextAttr parses the field key=value, both may be quoted.
+func parseTextAttr(field string) (string, string, error) {
+	key, value, ok := strings.Cut(field, string(textComponentSep))
+	if strings.HasPrefix(field, `"`) {
+		q, err := strconv.QuotedPrefix(field)
+		if err != nil {
+			return "", "", err
+		}
+		key = q
+		value, ok = strings.CutPrefix(field[len(q):], string(textComponentSep))
+	}
+	if !ok {
+		return "", "", strconv.ErrSyntax
+	}
+	key, err := parseTextValue(key)
+	if err != nil {
+		return "", "", err
+	}
+	value, err = parseTextValue(value)
+	return key, value, err
+}
+
+// parseTextValue unquotes s if it's quoted, a bare one can't have '=' or '"'.
+func parseTextValue(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		return strconv.Unquote(s)
+	}
+	if s == "" || strings.ContainsAny(s, `="`) {
+		return "", strconv.ErrSyntax
+	}
+	return s, nil
+}
+
+// splitTextFields splits line on separators which are not quoted.
+func splitTextFields(line string) ([]string, error) {
+	var fields []string
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, string(textAttrSep))
+		i := 0
+		for i < len(line) && line[i] != textAttrSep {
+			if line[i] != '"' {
+				i++
+				continue
+			}
+			q, err := strconv.QuotedPrefix(line[i:])
+			if err != nil {
+				return nil, err
+			}
+			i += len(q)
+		}
+		if i > 0 {
+			fields = append(fields, line[:i])
+		}
+		line = line[i:]
+	}
+	return fields, nil
+}
@@ -0,0 +1,175 @@
+package shandler
+
+import (
+	"math"
+	"sync"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"log/slog"
+)
+
+const (
+	// hashHues is the number of the hashed colors, their hues are spaced evenly in HCL.
+	hashHues = 24
+
+	// hashChroma and the lightness of the hashed colors for light and dark background.
+	hashChroma      = 0.55
+	hashLightL      = 0.5
+	hashDarkL       = 0.75
+	minHashContrast = 4.5 // the AA level of WCAG
+)
+
+// hashColors colors the prefixes and the values of keys by their hash,
+// so that each one has a stable color. It's shared by the clones.
+type hashColors struct {
+	prefix bool
+	keys   map[string]struct{}
+
+	// cache the *Theme of hashKey, the size is bounded by hashHues
+	// for each of the base themes
+	cache sync.Map
+}
+
+type hashKey struct {
+	base *Theme
+	hue  uint32
+}
+
+// hashColors returns the hashColors of h, it's created if there's none.
+func (h *baseHandler) hashColors() *hashColors {
+	if h.hash == nil {
+		h.hash = &hashColors{keys: make(map[string]struct{})}
+	}
+	return h.hash
+}
+
+// prefixTheme returns the theme of the handler's prefix, refer to updatePrefixTheme.
+func (h *baseHandler) prefixTheme() *Theme {
+	if h.prefixStyle != nil {
+		return h.prefixStyle
+	}
+	return h.themes[ThemePrefix]
+}
+
+// updatePrefixTheme hashes the theme of the prefix once it or the themes are changed.
+func (h *baseHandler) updatePrefixTheme() {
+	h.prefixStyle = nil
+	if h.hash != nil && h.hash.prefix && h.prefix != "" {
+		h.prefixStyle = h.hashedTheme(h.themes[ThemePrefix], hashString(h.prefix))
+	}
+}
+
+// keyTheme returns the hashed theme of the value if key or the full key
+// with the groups is chosen by WithHashedKeys, base is returned if not.
+func (h *baseHandler) keyTheme(base *Theme, key, fullKey string, v slog.Value) *Theme {
+	if h.hash == nil || len(h.hash.keys) == 0 {
+		return base
+	}
+	_, ok := h.hash.keys[fullKey]
+	if !ok {
+		_, ok = h.hash.keys[key]
+	}
+	if !ok {
+		return base
+	}
+	return h.hashedTheme(base, hashValue(v))
+}
+
+// hashedTheme returns base with the foreground picked by hash,
+// base is returned if the output isn't colored.
+func (h *baseHandler) hashedTheme(base *Theme, hash uint32) *Theme {
+	if !h.colored || base == nil {
+		return base
+	}
+	k := hashKey{base: base, hue: hash % hashHues}
+	if t, ok := h.hash.cache.Load(k); ok {
+		return t.(*Theme)
+	}
+
+	t := *base
+	t.fg = &colorPair{light: hashedColor(k.hue, false), dark: hashedColor(k.hue, true)}
+	t.formatVariants(base.profile)
+	v, _ := h.hash.cache.LoadOrStore(k, &t)
+	return v.(*Theme)
+}
+
+// FNV-1a, refer to hash/fnv.
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// hashString returns the FNV-1a hash of s without allocating.
+func hashString(s string) uint32 {
+	hash := uint32(fnvOffset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= fnvPrime32
+	}
+	return hash
+}
+
+// hashValue returns the hash of v, the values of the basic kinds are
+// hashed without allocating, the others by their string.
+func hashValue(v slog.Value) uint32 {
+	var bits uint64
+	switch v.Kind() {
+	case slog.KindString:
+		return hashString(v.String())
+	case slog.KindInt64:
+		bits = uint64(v.Int64())
+	case slog.KindUint64:
+		bits = v.Uint64()
+	case slog.KindFloat64:
+		bits = math.Float64bits(v.Float64())
+	case slog.KindBool:
+		if v.Bool() {
+			bits = 1
+		}
+	case slog.KindDuration:
+		bits = uint64(v.Duration())
+	case slog.KindTime:
+		bits = uint64(v.Time().UnixNano())
+	default:
+		return hashString(v.String())
+	}
+	hash := uint32(fnvOffset32)
+	for i := 0; i < 8; i++ {
+		hash ^= uint32(bits >> (8 * i) & 0xff)
+		hash *= fnvPrime32
+	}
+	return hash
+}
+
+// hashedColor returns the color of hue, it keeps minHashContrast
+// against a white background if light or a black one if dark.
+func hashedColor(hue uint32, dark bool) colorful.Color {
+	h := float64(hue) * 360 / hashHues
+	if dark {
+		c := colorful.Hcl(h, hashChroma, hashDarkL).Clamped()
+		return ensureContrast(c, colorful.Color{}, minHashContrast)
+	}
+	c := colorful.Hcl(h, hashChroma, hashLightL).Clamped()
+	return ensureContrast(c, colorful.Color{R: 1, G: 1, B: 1}, minHashContrast)
+}
+
+// WithHashedPrefix colors each prefix by its hash instead of the color of ThemePrefix,
+// so the subsystems logging by CopyWithPrefix are told apart. The attributes
+// of ThemePrefix like bold are kept.
+func WithHashedPrefix() Option {
+	return func(cfg *baseHandler) {
+		cfg.hashColors().prefix = true
+	}
+}
+
+// WithHashedKeys colors the values of keys by their hash in the text handler, e.g.
+// "request_id" so that the interleaved records of a request can be followed.
+// A key matches the attr key with or without the groups like "http.request_id".
+func WithHashedKeys(keys ...string) Option {
+	return func(cfg *baseHandler) {
+		hash := cfg.hashColors()
+		for _, key := range keys {
+			hash.keys[key] = struct{}{}
+		}
+	}
+}
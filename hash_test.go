@@ -0,0 +1,81 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"log/slog"
+)
+
+func TestHashedColors(t *testing.T) {
+	white, black := colorful.Color{R: 1, G: 1, B: 1}, colorful.Color{}
+	for hue := uint32(0); hue < hashHues; hue++ {
+		if r := contrastRatio(hashedColor(hue, false), white); r < minHashContrast {
+			t.Errorf("hue %d: contrast on light is %.2f", hue, r)
+		}
+		if r := contrastRatio(hashedColor(hue, true), black); r < minHashContrast {
+			t.Errorf("hue %d: contrast on dark is %.2f", hue, r)
+		}
+	}
+
+	var buf bytes.Buffer
+	h := NewTextHandler(
+		WithWriter(&buf),
+		WithColorMode(ColorAlways),
+		WithColorProfile(ProfileTrueColor),
+		WithBackground(BackgroundDark),
+		WithHashedPrefix(),
+		WithHashedKeys("request_id"),
+	)
+	sequence := regexp.MustCompile(`\x1b\[(38;2;\d+;\d+;\d+);1m\[(\w+)\]:.*request_id\x1b\[0m=\x1b\[(38;2;\d+;\d+;\d+)m`)
+	colors := make(map[string]string)
+	for _, prefix := range []string{"db", "http", "cache", "db"} {
+		buf.Reset()
+		slog.New(h.WithPrefix(prefix)).Info("", "request_id", prefix)
+		m := sequence.FindStringSubmatch(buf.String())
+		if m == nil {
+			t.Fatalf("got %q, want colored prefix and request_id", buf.String())
+		}
+		if m[1] != m[3] {
+			t.Errorf("%s: prefix %q and value %q of the same name differ", prefix, m[1], m[3])
+		}
+		if c, ok := colors[prefix]; ok && c != m[1] {
+			t.Errorf("%s: got %q, want the stable %q", prefix, m[1], c)
+		}
+		colors[prefix] = m[1]
+	}
+	if colors["db"] == colors["http"] && colors["http"] == colors["cache"] {
+		t.Errorf("all the prefixes have the same color %q", colors["db"])
+	}
+
+	// other keys keep the theme of their kind
+	buf.Reset()
+	slog.New(h).Info("", "id", "db")
+	if got, want := buf.String(), "=\x1b[38;2;165;214;167mdb"; !bytes.Contains([]byte(got), []byte(want)) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// TestHashedAllocs checks the hashed colors don't allocate once they're cached.
+func TestHashedAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+	record.AddAttrs(slog.String("request_id", "abc"), slog.Int("user", 42))
+	allocs := func(opts ...Option) float64 {
+		h := NewTextHandler(append(opts, WithWriter(io.Discard), WithColorMode(ColorAlways))...).WithPrefix("db")
+		return testing.AllocsPerRun(100, func() {
+			_ = h.Handle(context.Background(), record)
+		})
+	}
+	got := allocs(WithHashedPrefix(), WithHashedKeys("request_id", "user"))
+	if want := allocs(); got > want {
+		t.Errorf("got %v allocs per record, want %v", got, want)
+	}
+}
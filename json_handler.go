@@ -1,8 +1,15 @@
 package shandler
 
 import (
-	"log/slog"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"log/slog"
 )
 
 const (
@@ -19,41 +26,455 @@ func NewJsonHandler(opts ...Option) *JsonHandler {
 }
 
 func (j *JsonHandler) WithPrefix(prefix string) slog.Handler {
-	h := j.clone()
-	h.initThemes()
-	h.prefix = prefix
-	return &JsonHandler{h}
+	return &JsonHandler{j.withPrefix(prefix)}
+}
+
+func (j *JsonHandler) WithThemes(themes Themes) slog.Handler {
+	return &JsonHandler{j.withThemes(themes)}
+}
+
+func (j *JsonHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &JsonHandler{j.withAttrs(attrs)}
+}
+
+func (j *JsonHandler) WithGroup(name string) slog.Handler {
+	return &JsonHandler{j.withGroup(name)}
 }
 
 type jsonBuilder struct {
 	*baseBuilder
+	sep     bool      // a separator is needed before the next key
+	pending []string  // groups started but not written, they're opened with the first attr
+	opened  int       // groups written by this builder which are still open
+	pbuf    [4]string // backing array of pending
+}
+
+func (h *baseHandler) createJsonBuilder(buf *Buffer, r slog.Record) *jsonBuilder {
+	b := &jsonBuilder{baseBuilder: h.createBaseBuilder(buf, r)}
+	b.pending = b.pbuf[:0]
+	return b
 }
 
 func (b *jsonBuilder) start() {
-	b.buf.WriteString(b.h.themes[ThemeBracket].Render("{"))
+	b.h.WriteColorful(ThemeBracket, b.buf, "{")
 }
 
+// close closes every group opened by the handler or the record and the object itself.
 func (b *jsonBuilder) close() {
-	b.buf.WriteString(b.h.themes[ThemeBracket].Render("}"))
+	for i := b.opened + b.h.nOpenGroups; i > 0; i-- {
+		b.h.WriteColorful(ThemeBracket, b.buf, "}")
+	}
+	b.h.WriteColorful(ThemeBracket, b.buf, "}")
+}
+
+func (b *jsonBuilder) appendKey(key string) {
+	if b.sep {
+		b.buf.WriteByte(jsonAttrSep)
+	}
+	b.appendString(ThemeKey, key)
+	b.buf.WriteByte(jsonComponentSep)
+	b.sep = true
+}
+
+// appendString writes s as a JSON string colored with schema,
+// the escaping is done in place so that it doesn't allocate.
+func (b *jsonBuilder) appendString(schema ThemeSchema, s string) {
+	b.appendThemedString(b.h.themes[schema], s)
+}
+
+func (b *jsonBuilder) appendThemedString(theme *Theme, s string) {
+	colored := b.h.beginTheme(theme, b.buf)
+	b.buf.WriteByte('"')
+	*b.buf = appendEscapedJSONString(*b.buf, s)
+	b.buf.WriteByte('"')
+	if colored {
+		b.h.endColorful(b.buf)
+	}
+}
+
+func (b *jsonBuilder) appendError(err error) {
+	b.appendString(ThemeErrorValue, fmt.Sprintf("!ERROR:%v", err))
+}
+
+// appendMarshal writes the JSON encoding of v, when the output is colored
+// the schema is picked by the kind of the encoded value.
+func (b *jsonBuilder) appendMarshal(v any) {
+	if !b.h.colored {
+		if err := appendJSONMarshal(b.buf, v); err != nil {
+			b.appendError(err)
+		}
+		return
+	}
+
+	tmp := NewBuffer()
+	defer tmp.Free()
+	if err := appendJSONMarshal(tmp, v); err != nil {
+		b.appendError(err)
+		return
+	}
+	b.h.WriteColorful(jsonSchema((*tmp)[0]), b.buf, tmp.String())
+}
+
+// jsonSchema returns the schema of the JSON value starting with c,
+// objects and arrays are not colored as a whole.
+func jsonSchema(c byte) ThemeSchema {
+	switch c {
+	case '"':
+		return ThemeString
+	case 'n':
+		return ThemeNull
+	case 't', 'f':
+		return ThemeBool
+	case '{', '[':
+		return 0
+	default:
+		return ThemeNumber
+	}
+}
+
+// appendTime If r.Time is the zero time, ignore the time.
+func (b *jsonBuilder) appendTime() {
+	if b.r.Time.IsZero() {
+		return
+	}
+
+	a, ok := b.replaceBuiltin(slog.Time(slog.TimeKey, b.r.Time))
+	if !ok {
+		return
+	}
+	b.appendKey(a.Key)
+	if a.Value.Kind() == slog.KindTime {
+		b.appendString(ThemeTime, a.Value.Time().Format(b.h.timeFormat))
+	} else {
+		b.appendValue(a.Value)
+	}
+}
+
+func (b *jsonBuilder) appendLevel() {
+	if b.h.replacer == nil {
+		b.appendKey(slog.LevelKey)
+		b.appendLevelValue(b.r.Level)
+		return
+	}
+
+	a, ok := b.replaceBuiltin(slog.Any(slog.LevelKey, b.r.Level))
+	if !ok {
+		return
+	}
+	b.appendKey(a.Key)
+	if l, isLevel := a.Value.Any().(slog.Level); isLevel {
+		b.appendLevelValue(l)
+	} else {
+		b.appendValue(a.Value)
+	}
+}
+
+func (b *jsonBuilder) appendLevelValue(l slog.Level) {
+	b.appendThemedString(b.h.levelTheme(b.h.findLevel(l)), b.h.levelName(l))
+}
+
+// appendCaller If r.PC is zero or disabled caller, ignore it.
+func (b *jsonBuilder) appendCaller() {
+	if !b.h.caller || b.r.PC <= 0 {
+		return
+	}
+
+	src := b.source()
+	if b.h.replacer == nil {
+		b.appendKey(slog.SourceKey)
+		b.appendString(ThemeCaller, b.caller(src))
+		return
+	}
+
+	a, ok := b.replaceBuiltin(slog.Any(slog.SourceKey, &src))
+	if !ok {
+		return
+	}
+	b.appendKey(a.Key)
+	if s, isSource := a.Value.Any().(*slog.Source); isSource {
+		b.appendString(ThemeCaller, b.caller(*s))
+	} else {
+		b.appendValue(a.Value)
+	}
+}
+
+func (b *jsonBuilder) appendPrefix() {
+	if b.h.prefix == "" {
+		return
+	}
+
+	b.appendKey(prefixKey)
+	b.appendThemedString(b.h.prefixTheme(), b.h.prefix)
+}
+
+func (b *jsonBuilder) appendMessage() {
+	a, ok := b.replaceBuiltin(slog.String(slog.MessageKey, b.r.Message))
+	if !ok {
+		return
+	}
+	b.appendKey(a.Key)
+	b.appendValue(a.Value)
+}
+
+func (b *jsonBuilder) appendAttrs() {
+	if len(b.h.preformatted) > 0 {
+		// the builtins may be all removed by the Replacer
+		if b.sep {
+			b.buf.WriteByte(jsonAttrSep)
+		}
+		_, _ = b.buf.Write(b.h.preformatted)
+		b.sep = true
+	}
+	b.openGroups()
+	b.r.Attrs(func(a slog.Attr) bool {
+		b.appendAttr(a)
+		return true
+	})
+}
+
+// preformat renders attrs into the handler's preformatted buffer, without
+// a separator before the first one as it's written by appendAttrs. Only the groups which got attributes are written and counted as opened,
+// the others stay pending until an attribute shows up.
+func (b *jsonBuilder) preformat(attrs []slog.Attr) {
+	defer b.freeGroups()
+	b.sep = len(*b.buf) > 0
+	b.openGroups()
+	for _, a := range attrs {
+		b.appendAttr(a)
+	}
+	b.h.nOpenGroups += b.opened
+}
+
+func (b *jsonBuilder) openGroups() {
+	for _, name := range b.h.groups[b.h.nOpenGroups:] {
+		b.openGroup(name)
+	}
+}
+
+func (b *jsonBuilder) openGroup(name string) {
+	b.pending = append(b.pending, name)
+	if b.groups != nil {
+		*b.groups = append(*b.groups, name)
+	}
+}
+
+func (b *jsonBuilder) closeGroup() {
+	if n := len(b.pending); n > 0 {
+		b.pending = b.pending[:n-1]
+	} else {
+		b.h.WriteColorful(ThemeBracket, b.buf, "}")
+		b.opened--
+	}
+	if b.groups != nil {
+		*b.groups = (*b.groups)[:len(*b.groups)-1]
+	}
 }
 
-func (b *jsonBuilder) componentSep() byte {
-	return jsonComponentSep
+// writePending writes the groups which haven't been written yet,
+// it's called right before the first attribute inside them.
+func (b *jsonBuilder) writePending() {
+	for _, name := range b.pending {
+		b.appendKey(name)
+		b.h.WriteColorful(ThemeBracket, b.buf, "{")
+		b.sep = false
+		b.opened++
+	}
+	b.pending = b.pending[:0]
+}
+
+// appendAttr If an Attr's key and value are both the zero value, ignore the Attr.
+func (b *jsonBuilder) appendAttr(a slog.Attr) {
+	a = b.resolve(a)
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		b.writePending()
+		b.appendKey(a.Key)
+		b.appendValue(a.Value)
+		return
+	}
+
+	if attrs := a.Value.Group(); len(attrs) > 0 {
+		if a.Key != "" {
+			b.openGroup(a.Key)
+		}
+		for _, attr := range attrs {
+			b.appendAttr(attr)
+		}
+		if a.Key != "" {
+			b.closeGroup()
+		}
+	}
 }
 
-func (b *jsonBuilder) attrSep() byte {
-	return jsonAttrSep
+func (b *jsonBuilder) appendValue(v slog.Value) {
+	var colored bool
+	switch v.Kind() {
+	case slog.KindString:
+		b.appendString(ThemeString, v.String())
+	case slog.KindInt64:
+		colored = b.h.beginColorful(ThemeNumber, b.buf)
+		*b.buf = strconv.AppendInt(*b.buf, v.Int64(), 10)
+	case slog.KindUint64:
+		colored = b.h.beginColorful(ThemeNumber, b.buf)
+		*b.buf = strconv.AppendUint(*b.buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		// json.Marshal is funny about floats; it doesn't
+		// always match strconv.AppendFloat. So just call it.
+		b.appendMarshal(v.Float64())
+	case slog.KindBool:
+		colored = b.h.beginColorful(ThemeBool, b.buf)
+		*b.buf = strconv.AppendBool(*b.buf, v.Bool())
+	case slog.KindDuration:
+		// Do what json.Marshal does.
+		colored = b.h.beginColorful(ThemeDuration, b.buf)
+		*b.buf = strconv.AppendInt(*b.buf, int64(v.Duration()), 10)
+	case slog.KindTime:
+		b.appendTimeValue(v.Time())
+	default:
+		a := v.Any()
+		_, jm := a.(json.Marshaler)
+		if err, ok := a.(error); ok && !jm {
+			b.appendString(ThemeErrorValue, err.Error())
+		} else {
+			b.appendMarshal(a)
+		}
+	}
+	if colored {
+		b.h.endColorful(b.buf)
+	}
 }
 
-func (b *jsonBuilder) appendTime(t time.Time) {
-	if t.IsZero() {
+// appendTimeValue Adapted from time.Time.MarshalJSON to avoid allocation.
+func (b *jsonBuilder) appendTimeValue(t time.Time) {
+	if y := t.Year(); y < 0 || y >= 10000 {
+		// RFC 3339 is clear that years are 4 digits exactly.
+		b.appendError(fmt.Errorf("time.Time year outside of range [0,9999]"))
 		return
 	}
+	colored := b.h.beginColorful(ThemeTimeValue, b.buf)
+	b.buf.WriteByte('"')
+	*b.buf = t.AppendFormat(*b.buf, time.RFC3339Nano)
+	b.buf.WriteByte('"')
+	if colored {
+		b.h.endColorful(b.buf)
+	}
+}
+
+func (b *jsonBuilder) output() *Buffer {
+	b.buf.WriteByte('\n')
+	return b.buf
+}
+
+type jsonEncoder struct {
+	buf *bytes.Buffer
+	// Use a json.Encoder to avoid escaping HTML.
+	json *json.Encoder
+}
+
+var jsonEncoderPool = sync.Pool{
+	New: func() any {
+		enc := &jsonEncoder{buf: new(bytes.Buffer)}
+		enc.json = json.NewEncoder(enc.buf)
+		enc.json.SetEscapeHTML(false)
+		return enc
+	},
+}
+
+func appendJSONMarshal(buf *Buffer, v any) error {
+	j := jsonEncoderPool.Get().(*jsonEncoder)
+	defer func() {
+		// To reduce peak allocation, return only smaller buffers to the pool.
+		const maxBufferSize = 16 << 10
+		if j.buf.Cap() > maxBufferSize {
+			return
+		}
+		j.buf.Reset()
+		jsonEncoderPool.Put(j)
+	}()
+
+	if err := j.json.Encode(v); err != nil {
+		return err
+	}
 
-	//b.buf.WriteString(timeTheme.Render(t.Format(b.opts.timeFormat)))
-	b.buf.WriteByte(b.componentSep())
+	bs := j.buf.Bytes()
+	_, _ = buf.Write(bs[:len(bs)-1]) // remove final newline
+	return nil
 }
 
-func (b *jsonBuilder) appendLevel(slog.Level) {
+// appendEscapedJSONString escapes s for JSON and appends it to buf.
+// It does not surround the string in quotation marks.
+//
+// Modified from encoding/json/encode.go:encodeState.string,
+// with escapeHTML set to false.
+func appendEscapedJSONString(buf []byte, s string) []byte {
+	char := func(b byte) { buf = append(buf, b) }
+	str := func(s string) { buf = append(buf, s...) }
 
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if safeSet[b] {
+				i++
+				continue
+			}
+			if start < i {
+				str(s[start:i])
+			}
+			char('\\')
+			switch b {
+			case '\\', '"':
+				char(b)
+			case '\n':
+				char('n')
+			case '\r':
+				char('r')
+			case '\t':
+				char('t')
+			default:
+				// This encodes bytes < 0x20 except for \t, \n and \r.
+				str(`u00`)
+				char(hex[b>>4])
+				char(hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			if start < i {
+				str(s[start:i])
+			}
+			str(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 is LINE SEPARATOR.
+		// U+2029 is PARAGRAPH SEPARATOR.
+		// They are both technically valid characters in JSON strings,
+		// but don't work in JSONP, which has to be evaluated as JavaScript,
+		// and can lead to security holes there. It is valid JSON to
+		// escape them, so we do so unconditionally.
+		if c == '\u2028' || c == '\u2029' {
+			if start < i {
+				str(s[start:i])
+			}
+			str(`\u202`)
+			char(hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	if start < len(s) {
+		str(s[start:])
+	}
+	return buf
 }
+
+const hex = "0123456789abcdef"
@@ -0,0 +1,262 @@
+package shandler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// layoutField is a field of the Layout template.
+type layoutField int
+
+const (
+	fieldLiteral layoutField = iota
+	fieldTime
+	fieldLevel
+	fieldCaller
+	fieldPrefix
+	fieldMessage
+	fieldAttrs
+)
+
+var layoutFields = map[string]layoutField{
+	"time":    fieldTime,
+	"level":   fieldLevel,
+	"caller":  fieldCaller,
+	"prefix":  fieldPrefix,
+	"msg":     fieldMessage,
+	"message": fieldMessage,
+	"attrs":   fieldAttrs,
+}
+
+// Alignments of the fields with width.
+const (
+	alignLeft   = '<'
+	alignRight  = '>'
+	alignCenter = '^'
+)
+
+// layoutPart is either a literal or a field of the Layout.
+type layoutPart struct {
+	field   layoutField
+	literal string
+	width   int
+	align   byte
+}
+
+// Layout orders the components of the text handler's records, it's compiled from
+// a template by ParseLayout. The fields in braces are time, level, caller, prefix,
+// msg and attrs, the others are literal text, "{{" and "}}" are literal braces.
+//
+//	{time} {level:5} {prefix} {msg} {attrs} {caller}
+//
+// A field can have a min width after ':', the content is padded with spaces
+// aligned by '<' (the default), '>' or '^' before the width, e.g. {level:>5}.
+// The width doesn't count the color sequences.
+//
+// A field which renders nothing is collapsed with the literal text before it,
+// so the separators of the empty fields don't pile up. The text before the
+// first field and after the last one is always written.
+type Layout struct {
+	template string
+	parts    []layoutPart
+	caller   bool
+}
+
+// ParseLayout compiles the layout template, refer to Layout.
+func ParseLayout(template string) (*Layout, error) {
+	l := &Layout{template: template}
+	var literal strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c == '}' {
+			if i+1 < len(template) && template[i+1] == '}' {
+				i++
+			} else {
+				return nil, fmt.Errorf("shandler: layout %q: unexpected } at %d", template, i)
+			}
+		}
+		if c != '{' {
+			literal.WriteByte(c)
+			continue
+		}
+		if i+1 < len(template) && template[i+1] == '{' {
+			literal.WriteByte(c)
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(template[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("shandler: layout %q: unterminated { at %d", template, i)
+		}
+		part, err := parseLayoutField(template[i+1 : i+end])
+		if err != nil {
+			return nil, fmt.Errorf("shandler: layout %q: %w", template, err)
+		}
+		if literal.Len() > 0 || len(l.parts) > 0 {
+			l.parts = append(l.parts, layoutPart{literal: literal.String()})
+			literal.Reset()
+		}
+		l.parts = append(l.parts, part)
+		l.caller = l.caller || part.field == fieldCaller
+		i += end
+	}
+	if literal.Len() > 0 {
+		l.parts = append(l.parts, layoutPart{literal: literal.String()})
+	}
+	return l, nil
+}
+
+// MustParseLayout is like ParseLayout but panics if the template is invalid.
+func MustParseLayout(template string) *Layout {
+	l, err := ParseLayout(template)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// parseLayoutField parses the field like "level:>5" between the braces.
+func parseLayoutField(s string) (layoutPart, error) {
+	name, spec, _ := strings.Cut(s, ":")
+	field, ok := layoutFields[strings.TrimSpace(name)]
+	if !ok {
+		return layoutPart{}, fmt.Errorf("unknown field %q", name)
+	}
+	part := layoutPart{field: field, align: alignLeft}
+	if spec == "" {
+		return part, nil
+	}
+	if c := spec[0]; c == alignLeft || c == alignRight || c == alignCenter {
+		part.align = c
+		spec = spec[1:]
+	}
+	width, err := strconv.Atoi(spec)
+	if err != nil || width < 0 {
+		return layoutPart{}, fmt.Errorf("invalid width %q of %s", spec, name)
+	}
+	part.width = width
+	return part, nil
+}
+
+// String returns the template of l.
+func (l *Layout) String() string {
+	return l.template
+}
+
+// appendLayout writes the components in the order of the layout, the builder
+// doesn't separate them by itself so the literals of the layout do it.
+func (b *textBuilder) appendLayout(l *Layout) {
+	b.layout = true
+	var sep string   // the literal waiting for the next field
+	written := false // whether a field has been written
+	for i, part := range l.parts {
+		switch {
+		case part.field != fieldLiteral:
+		case i == 0 || i == len(l.parts)-1:
+			// the text before the first field and after the last one
+			b.buf.WriteString(part.literal)
+			continue
+		case written:
+			sep = part.literal
+			continue
+		default:
+			continue
+		}
+
+		mark := len(*b.buf)
+		b.buf.WriteString(sep)
+		start := len(*b.buf)
+		b.appendField(part.field)
+		if len(*b.buf) == start {
+			*b.buf = (*b.buf)[:mark]
+			continue
+		}
+		b.pad(start, part)
+		sep, written = "", true
+	}
+}
+
+func (b *textBuilder) appendField(field layoutField) {
+	switch field {
+	case fieldTime:
+		b.appendTime()
+	case fieldLevel:
+		b.appendLevel()
+	case fieldCaller:
+		b.appendCaller()
+	case fieldPrefix:
+		b.appendPrefix()
+	case fieldMessage:
+		b.appendMessage()
+	case fieldAttrs:
+		start := len(*b.buf)
+		b.appendAttrs()
+		// every attr is led by a separator, the layout has its own
+		if len(*b.buf) > start && (*b.buf)[start] == textAttrSep {
+			*b.buf = append((*b.buf)[:start], (*b.buf)[start+1:]...)
+		}
+	}
+}
+
+// pad pads the field written from start to the width of part.
+func (b *textBuilder) pad(start int, part layoutPart) {
+	n := part.width - visibleWidth((*b.buf)[start:])
+	if n <= 0 {
+		return
+	}
+	left := 0
+	switch part.align {
+	case alignRight:
+		left = n
+	case alignCenter:
+		left = n / 2
+	}
+	end := len(*b.buf)
+	for i := 0; i < n; i++ {
+		b.buf.WriteByte(' ')
+	}
+	if left > 0 {
+		buf := *b.buf
+		copy(buf[start+left:], buf[start:end])
+		for i := start; i < start+left; i++ {
+			buf[i] = ' '
+		}
+	}
+}
+
+// visibleWidth returns the number of runes of s without the color sequences.
+func visibleWidth(s []byte) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == ESC && i+1 < len(s) && s[i+1] == '[' {
+			i += 2
+			for i < len(s) && (s[i] < '@' || s[i] > '~') {
+				i++
+			}
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRune(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// WithLayout renders the records of the text handler in the order of layout
+// instead of time, level, caller, prefix, message and attrs. The caller is
+// enabled if the layout has it. It only applies to the text handler, the
+// JSON handler and the Encoder ignore the order of the layout.
+//
+//	WithLayout(MustParseLayout("{time} {level:5} {prefix} {msg} {attrs} {caller}"))
+func WithLayout(layout *Layout) Option {
+	return func(cfg *baseHandler) {
+		cfg.layout = layout
+		if layout != nil && layout.caller {
+			cfg.caller = true
+		}
+	}
+}
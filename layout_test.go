@@ -0,0 +1,74 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+)
+
+func TestLayout(t *testing.T) {
+	record := slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), slog.LevelWarn, "hello", 0)
+	record.AddAttrs(slog.Int("n", 1), slog.String("s", "x"))
+
+	tests := []struct {
+		layout string
+		prefix string
+		record slog.Record
+		want   string
+	}{
+		{"{time} {level:5} {prefix} {msg} {attrs}", "db", record, "03:04:05.000 WARN  [db]: hello n=1 s=x"},
+		{"{level:>6}|{msg:^9}|", "", record, "  WARN|  hello  |"},
+		{"{time} {level} {prefix} {msg} {attrs}", "", record, "03:04:05.000 WARN hello n=1 s=x"},
+		{"[{level}] {msg} - {attrs}", "", slog.NewRecord(time.Time{}, slog.LevelInfo, "bye", 0), "[INFO] bye"},
+		{"{{{msg}}}", "", record, "{hello}"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		h := NewTextHandler(WithWriter(&buf), WithPrefix(tt.prefix), WithLayout(MustParseLayout(tt.layout)))
+		if err := h.Handle(context.Background(), tt.record); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.layout, got, tt.want)
+		}
+	}
+
+	// the padding doesn't count the color sequences
+	var buf bytes.Buffer
+	h := NewTextHandler(WithWriter(&buf), WithColorMode(ColorAlways), WithColorProfile(ProfileAscii),
+		WithLayout(MustParseLayout("{level:6}|")))
+	_ = h.Handle(context.Background(), record)
+	if got, want := buf.String(), "\x1b[1mWARN\x1b[0m  |\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, layout := range []string{"{msg", "{msg}}", "{message:x}", "{name}"} {
+		if _, err := ParseLayout(layout); err == nil {
+			t.Errorf("%s: want error", layout)
+		}
+	}
+}
+
+// TestLayoutAllocs checks the layout doesn't allocate more than the fixed order.
+func TestLayoutAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+	record.AddAttrs(slog.Int("n", 1))
+	allocs := func(h *TextHandler) float64 {
+		return testing.AllocsPerRun(100, func() {
+			_ = h.Handle(context.Background(), record)
+		})
+	}
+	layout := MustParseLayout("{time} {level:5} {prefix} {msg} {attrs}")
+	got := allocs(NewTextHandler(WithWriter(io.Discard), WithLayout(layout)))
+	if want := allocs(NewTextHandler(WithWriter(io.Discard))); got > want {
+		t.Errorf("got %v allocs per record, want %v", got, want)
+	}
+}
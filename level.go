@@ -0,0 +1,86 @@
+package shandler
+
+import (
+	"slices"
+
+	"log/slog"
+)
+
+// Levels commonly used beside the ones defined by slog,
+// register them with WithCustomLevel to give them a label and theme.
+const (
+	LevelTrace  slog.Level = -8
+	LevelNotice slog.Level = 2
+	LevelFatal  slog.Level = 12
+)
+
+// levelDef describes how records of a level are rendered.
+type levelDef struct {
+	level slog.Level
+	label string
+
+	// schema is the theme used when theme is nil
+	schema ThemeSchema
+	theme  *Theme
+
+	// message is the theme of the messages, refer to WithMessageTheme
+	message *Theme
+
+	// custom reports whether it's registered by WithCustomLevel
+	custom bool
+}
+
+var defaultLevels = []levelDef{
+	{level: slog.LevelDebug, label: "DBUG", schema: ThemeDebug},
+	{level: slog.LevelInfo, label: "INFO", schema: ThemeInfo},
+	{level: slog.LevelWarn, label: "WARN", schema: ThemeWarn},
+	{level: slog.LevelError, label: "ERRO", schema: ThemeError},
+}
+
+// addLevel registers def, replacing the one with the same level if any.
+// The levels are kept sorted so that findLevel can search them.
+func (h *baseHandler) addLevel(def levelDef) {
+	i, found := slices.BinarySearchFunc(h.levels, def.level, func(d levelDef, l slog.Level) int {
+		return int(d.level - l)
+	})
+	if found {
+		if def.message == nil {
+			def.message = h.levels[i].message
+		}
+		h.levels[i] = def
+		return
+	}
+	h.levels = slices.Insert(h.levels, i, def)
+}
+
+// findLevel returns the definition of the greatest level not above l,
+// levels lower than every definition use the lowest one.
+func (h *baseHandler) findLevel(l slog.Level) levelDef {
+	i, found := slices.BinarySearchFunc(h.levels, l, func(d levelDef, l slog.Level) int {
+		return int(d.level - l)
+	})
+	if found {
+		return h.levels[i]
+	}
+	if i == 0 {
+		return h.levels[0]
+	}
+	return h.levels[i-1]
+}
+
+// levelName returns the name of l used by structured output,
+// custom levels use their label and others follow slog.Level.String.
+func (h *baseHandler) levelName(l slog.Level) string {
+	if def := h.findLevel(l); def.custom && def.level == l {
+		return def.label
+	}
+	return l.String()
+}
+
+// levelTheme returns the theme records rendered with def are colored with.
+func (h *baseHandler) levelTheme(def levelDef) *Theme {
+	if def.theme != nil {
+		return def.theme
+	}
+	return h.themes[def.schema]
+}
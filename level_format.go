@@ -0,0 +1,153 @@
+package shandler
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"log/slog"
+)
+
+// LevelFormatter renders the level of the text handler. The label is the one
+// of the level definition the record falls into, e.g. "DBUG" or the one of
+// WithCustomLevel, name is the full name like "DEBUG", "INFO+2" or the
+// label of the custom level.
+type LevelFormatter func(level slog.Level, label, name string) string
+
+// Icons of the levels, refer to LevelIcons.
+var (
+	// NerdFontIcons requires a Nerd Font, https://www.nerdfonts.com
+	NerdFontIcons = map[slog.Level]string{
+		LevelTrace:      "\uf002",
+		slog.LevelDebug: "\uf188",
+		slog.LevelInfo:  "\uf05a",
+		LevelNotice:     "\uf0f3",
+		slog.LevelWarn:  "\uf071",
+		slog.LevelError: "\uf057",
+		LevelFatal:      "\U000f068c",
+	}
+
+	EmojiIcons = map[slog.Level]string{
+		LevelTrace:      "🔬",
+		slog.LevelDebug: "🐛",
+		slog.LevelInfo:  "💡",
+		LevelNotice:     "🔔",
+		slog.LevelWarn:  "⚠️",
+		slog.LevelError: "❌",
+		LevelFatal:      "💀",
+	}
+)
+
+// LevelLabel renders the label, it's the default.
+func LevelLabel(_ slog.Level, label, _ string) string {
+	return label
+}
+
+// LevelFullName renders the full name like slog, e.g. "DEBUG" and "WARN+2".
+func LevelFullName(_ slog.Level, _, name string) string {
+	return name
+}
+
+// LevelLowercase lowercases what f renders, LevelLabel is used if f is nil.
+func LevelLowercase(f LevelFormatter) LevelFormatter {
+	if f == nil {
+		f = LevelLabel
+	}
+	return func(level slog.Level, label, name string) string {
+		return strings.ToLower(f(level, label, name))
+	}
+}
+
+// LevelFixedWidth pads or truncates what f renders to width runes,
+// so the columns after the level are aligned. LevelLabel is used if f is nil.
+func LevelFixedWidth(f LevelFormatter, width int) LevelFormatter {
+	if f == nil {
+		f = LevelLabel
+	}
+	return func(level slog.Level, label, name string) string {
+		s := f(level, label, name)
+		if n := utf8.RuneCountInString(s); n < width {
+			return s + strings.Repeat(" ", width-n)
+		} else if n > width {
+			return string([]rune(s)[:width])
+		}
+		return s
+	}
+}
+
+// LevelIcons renders the icon of the greatest level not above the record's one,
+// e.g. NerdFontIcons or EmojiIcons. The label is rendered if there's none.
+func LevelIcons(icons map[slog.Level]string) LevelFormatter {
+	levels := make([]slog.Level, 0, len(icons))
+	for l := range icons {
+		levels = append(levels, l)
+	}
+	slices.Sort(levels)
+	return func(level slog.Level, label, _ string) string {
+		i, found := slices.BinarySearch(levels, level)
+		if found {
+			return icons[level]
+		}
+		if i == 0 {
+			return label
+		}
+		return icons[levels[i-1]]
+	}
+}
+
+// formatLevel renders the level by the formatter of the handler.
+func (h *baseHandler) formatLevel(l slog.Level, def levelDef) string {
+	if h.levelFormatter == nil {
+		return def.label
+	}
+	return h.levelFormatter(l, def.label, h.levelName(l))
+}
+
+// writeBadge writes s filled with the color of theme, padded by a space.
+func (h *baseHandler) writeBadge(theme *Theme, buf *Buffer, s string) {
+	if !h.beginTheme(theme, buf) {
+		buf.WriteString(s)
+		return
+	}
+	buf.Write(CSI)
+	buf.WriteString(ReverseSeq)
+	buf.WriteByte('m')
+	buf.WriteByte(' ')
+	buf.WriteString(s)
+	buf.WriteByte(' ')
+	h.endColorful(buf)
+}
+
+// WithLevelFormatter renders the level of the text handler by f, e.g.
+//
+//	WithLevelFormatter(LevelFixedWidth(LevelLowercase(LevelFullName), 5))
+func WithLevelFormatter(f LevelFormatter) Option {
+	return func(cfg *baseHandler) {
+		cfg.levelFormatter = f
+	}
+}
+
+// WithLevelBadge renders the level of the text handler as a badge
+// filled with the color of its theme, it's plain if not colored.
+func WithLevelBadge() Option {
+	return func(cfg *baseHandler) {
+		cfg.levelBadge = true
+	}
+}
+
+// WithMessageTheme renders the messages of the text handler with theme for the records
+// from level up to the next level registered by WithCustomLevel or the default ones.
+//
+//	WithMessageTheme(slog.LevelError, NewTheme().Foreground(red, red).Bold())
+//	WithMessageTheme(slog.LevelDebug, NewTheme().Faint())
+func WithMessageTheme(level slog.Level, theme *Theme) Option {
+	return func(cfg *baseHandler) {
+		def := cfg.findLevel(level)
+		if def.level != level {
+			// the records from level are rendered like the ones of def
+			def.level, def.custom = level, false
+		}
+		def.message = theme
+		cfg.addLevel(def)
+	}
+}
@@ -0,0 +1,74 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"log/slog"
+)
+
+func TestLevelFormatter(t *testing.T) {
+	levels := []slog.Level{LevelTrace, slog.LevelDebug, slog.LevelInfo + 2, slog.LevelError}
+	tests := []struct {
+		f    LevelFormatter
+		want []string
+	}{
+		{nil, []string{"TRAC", "DBUG", "INFO", "ERRO"}},
+		{LevelFullName, []string{"TRAC", "DEBUG", "INFO+2", "ERROR"}},
+		{LevelLowercase(nil), []string{"trac", "dbug", "info", "erro"}},
+		{LevelFixedWidth(LevelFullName, 5), []string{"TRAC ", "DEBUG", "INFO+", "ERROR"}},
+		{LevelIcons(EmojiIcons), []string{"🔬", "🐛", "🔔", "❌"}},
+		{LevelIcons(map[slog.Level]string{slog.LevelInfo: "i"}), []string{"TRAC", "DBUG", "i", "i"}},
+	}
+	for i, tt := range tests {
+		var buf bytes.Buffer
+		logger := slog.New(NewTextHandler(
+			WithWriter(&buf),
+			WithLevel(LevelTrace),
+			WithCustomLevel(LevelTrace, "TRAC", nil),
+			WithLevelFormatter(tt.f),
+		))
+		for _, l := range levels {
+			logger.Log(context.Background(), l, "msg")
+		}
+		for j, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if want := " " + tt.want[j] + " "; !strings.Contains(line, want) {
+				t.Errorf("%d: line %q doesn't contain %q", i, line, want)
+			}
+		}
+	}
+}
+
+func TestLevelBadgeAndMessageTheme(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewTextHandler(
+		WithWriter(&buf),
+		WithColorMode(ColorAlways),
+		WithColorProfile(ProfileAscii),
+		WithTheme(ThemeError, NewTheme().Bold()),
+		WithLevelBadge(),
+		WithMessageTheme(slog.LevelError, NewTheme().Underline()),
+		WithMessageTheme(slog.LevelDebug+2, NewTheme().Faint()),
+		WithLevel(slog.LevelDebug),
+	))
+	logger.Error("failed")
+	logger.Log(context.Background(), slog.LevelDebug+3, "debug")
+	logger.Info("info")
+
+	lines := strings.Split(buf.String(), "\n")
+	if want := "\x1b[1m\x1b[7m ERRO \x1b[0m"; !strings.Contains(lines[0], want) {
+		t.Errorf("got %q, want badge %q", lines[0], want)
+	}
+	if want := "\x1b[4mfailed\x1b[0m"; !strings.Contains(lines[0], want) {
+		t.Errorf("got %q, want message %q", lines[0], want)
+	}
+	// the debug records are rendered by the level inserted for the message theme
+	if want := " DBUG \x1b[0m \x1b[2mdebug\x1b[0m"; !strings.Contains(lines[1], want) {
+		t.Errorf("got %q, want %q", lines[1], want)
+	}
+	if !strings.HasSuffix(lines[2], " info") {
+		t.Errorf("got %q, want plain message", lines[2])
+	}
+}
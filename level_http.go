@@ -0,0 +1,84 @@
+package shandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"log/slog"
+)
+
+// maxLevelBodySize is the max size of the body accepted by LevelHandler.
+const maxLevelBodySize = 1 << 10
+
+type levelPayload struct {
+	Level *slog.Level `json:"level,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// LevelHandler returns an http.Handler which reports the level of v on GET and changes
+// it on PUT. The new level is read from the query parameter "level" or the body,
+// which is either JSON as {"level":"debug"} or the plain level name, the names are
+// parsed by slog.Level.UnmarshalText, e.g. "debug", "INFO+2".
+//
+//	level := new(slog.LevelVar)
+//	slog.SetDefault(slog.New(NewTextHandler(WithLevel(level))))
+//	http.Handle("/log/level", LevelHandler(level))
+func LevelHandler(v *slog.LevelVar) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			level, err := readLevel(r)
+			if err != nil {
+				writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+				return
+			}
+			v.Set(level)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeLevelPayload(w, http.StatusMethodNotAllowed, levelPayload{
+				Error: "only GET and PUT are supported",
+			})
+			return
+		}
+		level := v.Level()
+		writeLevelPayload(w, http.StatusOK, levelPayload{Level: &level})
+	})
+}
+
+func readLevel(r *http.Request) (slog.Level, error) {
+	var level slog.Level
+	if name := r.URL.Query().Get("level"); name != "" {
+		return level, level.UnmarshalText([]byte(name))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxLevelBodySize))
+	if err != nil {
+		return level, err
+	}
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) == 0 {
+		return level, errors.New("missing level")
+	}
+	if body[0] != '{' {
+		return level, level.UnmarshalText(body)
+	}
+
+	var payload levelPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return level, err
+	}
+	if payload.Level == nil {
+		return level, errors.New("missing level")
+	}
+	return *payload.Level, nil
+}
+
+func writeLevelPayload(w http.ResponseWriter, code int, payload levelPayload) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -0,0 +1,55 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"log/slog"
+)
+
+func TestLevelHandler(t *testing.T) {
+	level := new(slog.LevelVar)
+	var buf bytes.Buffer
+	logger := slog.New(NewTextHandler(WithWriter(&buf), WithLevel(level)))
+	prefixed := slog.New(logger.Handler().(Handler).WithPrefix("db"))
+	server := httptest.NewServer(LevelHandler(level))
+	defer server.Close()
+
+	tests := []struct {
+		method string
+		query  string
+		body   string
+		code   int
+		want   string
+	}{
+		{http.MethodGet, "", "", http.StatusOK, `{"level":"INFO"}`},
+		{http.MethodPut, "", "debug", http.StatusOK, `{"level":"DEBUG"}`},
+		{http.MethodPut, "", `{"level":"warn"}`, http.StatusOK, `{"level":"WARN"}`},
+		{http.MethodPut, "?level=DEBUG-4", "", http.StatusOK, `{"level":"DEBUG-4"}`},
+		{http.MethodPut, "", "verbose", http.StatusBadRequest, `{"error":"slog: level string \"verbose\": unknown name"}`},
+		{http.MethodPost, "", "", http.StatusMethodNotAllowed, `{"error":"only GET and PUT are supported"}`},
+		{http.MethodGet, "", "", http.StatusOK, `{"level":"DEBUG-4"}`},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, server.URL+test.query, strings.NewReader(test.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		_, _ = body.ReadFrom(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != test.code || strings.TrimSpace(body.String()) != test.want {
+			t.Errorf("%s %q: got %d %s, want %d %s", test.method, test.body,
+				resp.StatusCode, body.String(), test.code, test.want)
+		}
+	}
+
+	if !prefixed.Enabled(context.Background(), LevelTrace+1) {
+		t.Errorf("the level isn't shared by the cloned handler")
+	}
+}
@@ -1,7 +1,12 @@
 package shandler
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -78,3 +83,147 @@ func TestOutput(t *testing.T) {
 	})
 	logger.Info("with another caller theme logged")
 }
+
+func TestWithAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewTextHandler(WithWriter(&buf)))
+	logger = logger.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h")
+	logger.Info("message", "c", 3)
+	if _, ok := logger.Handler().(*TextHandler); !ok {
+		t.Fatalf("expected *TextHandler, got %T", logger.Handler())
+	}
+
+	want := " message a=1 g.b=2 g.h.c=3\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Fatalf("got %q, want suffix %q", got, want)
+	}
+}
+
+func TestJsonHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(WithWriter(&buf), WithPrefix("app")))
+	logger = logger.With("a", 1).WithGroup("g").With("b", 2).WithGroup("h")
+	logger.Info("quote \" <message>", "c", 3, slog.Group("empty"))
+	logger.Info("no attrs")
+
+	want := []string{
+		`"prefix":"app","msg":"quote \" <message>","a":1,"g":{"b":2,"h":{"c":3}}}`,
+		`"prefix":"app","msg":"no attrs","a":1,"g":{"b":2}}`,
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(want))
+	}
+	for i, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("invalid json: %s", line)
+		}
+		if !strings.HasSuffix(line, want[i]) {
+			t.Errorf("got %s, want suffix %s", line, want[i])
+		}
+	}
+}
+
+func TestJsonHandlerColored(t *testing.T) {
+	var plain, colored bytes.Buffer
+	record := func(h slog.Handler) {
+		slog.New(h).Info("message", "s", "str", "n", 1.5, "b", true, "nil", nil, slog.Group("g", "d", time.Second))
+	}
+	record(NewJsonHandler(WithWriter(&plain)))
+	record(NewJsonHandler(WithWriter(&colored), WithColorMode(ColorAlways)))
+
+	if !strings.Contains(colored.String(), "\x1b[") {
+		t.Fatalf("expected colored output, got %q", colored.String())
+	}
+	stripped := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(colored.String(), "")
+	if !json.Valid([]byte(stripped)) {
+		t.Fatalf("invalid json after stripping colors: %s", stripped)
+	}
+	trim := func(s string) string { return s[strings.Index(s, `"level"`):] }
+	if trim(stripped) != trim(plain.String()) {
+		t.Fatalf("got %s, want %s", stripped, plain.String())
+	}
+}
+
+func TestReplacerBuiltins(t *testing.T) {
+	replacer := func(groups []string, a slog.Attr) slog.Attr {
+		switch a.Key {
+		case slog.TimeKey:
+			return slog.Attr{}
+		case slog.LevelKey:
+			return slog.String("severity", strings.ToLower(a.Value.String()))
+		case slog.MessageKey:
+			return slog.String("message", strings.ToUpper(a.Value.String()))
+		case slog.SourceKey:
+			return slog.Attr{}
+		}
+		return a
+	}
+
+	var buf bytes.Buffer
+	slog.New(NewJsonHandler(WithWriter(&buf), WithCaller(), WithReplacer(replacer))).Info("hello", "k", "v")
+	if got, want := buf.String(), `{"severity":"info","message":"HELLO","k":"v"}`+"\n"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	buf.Reset()
+	slog.New(NewTextHandler(WithWriter(&buf), WithCaller(), WithReplacer(replacer))).Info("hello", "k", "v")
+	if got, want := buf.String(), "info HELLO k=v\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReplacerDropsBuiltins(t *testing.T) {
+	replacer := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+			return slog.Attr{}
+		}
+		return a
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewJsonHandler(WithWriter(&buf), WithReplacer(replacer)))
+	logger.With("a", 1).Info("x", "b", 2)
+	logger.With("a", 1).WithGroup("g").With("c", 3).Info("x", "b", 2)
+	logger.With("a", 1).Info("x")
+
+	want := []string{`{"a":1,"b":2}`, `{"a":1,"g":{"c":3,"b":2}}`, `{"a":1}`}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %q, want %q", lines, want)
+	}
+	for i, line := range lines {
+		if !json.Valid([]byte(line)) || line != want[i] {
+			t.Errorf("got %s, want %s", line, want[i])
+		}
+	}
+}
+
+func TestCustomLevel(t *testing.T) {
+	var text, js bytes.Buffer
+	opts := []Option{
+		WithLevel(LevelTrace),
+		WithCustomLevel(LevelTrace, "TRAC", nil),
+		WithCustomLevel(LevelFatal, "FATL", NewTheme().Bold()),
+	}
+	textLogger := slog.New(NewTextHandler(append(opts, WithWriter(&text))...))
+	jsonLogger := slog.New(NewJsonHandler(append(opts, WithWriter(&js))...))
+	for _, logger := range []*slog.Logger{textLogger, jsonLogger} {
+		logger.Log(context.Background(), LevelTrace, "trace")
+		logger.Log(context.Background(), slog.LevelDebug-2, "between")
+		logger.Log(context.Background(), slog.LevelError, "error")
+		logger.Log(context.Background(), LevelFatal+1, "fatal")
+	}
+
+	for i, want := range []string{" TRAC ", " TRAC ", " ERRO ", " FATL "} {
+		if line := strings.Split(text.String(), "\n")[i]; !strings.Contains(line, want) {
+			t.Errorf("text line %d: %q doesn't contain %q", i, line, want)
+		}
+	}
+	for i, want := range []string{`"TRAC"`, `"DEBUG-2"`, `"ERROR"`, `"ERROR+5"`} {
+		want = `"level":` + want
+		if line := strings.Split(js.String(), "\n")[i]; !strings.Contains(line, want) {
+			t.Errorf("json line %d: %q doesn't contain %q", i, line, want)
+		}
+	}
+}
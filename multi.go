@@ -0,0 +1,97 @@
+package shandler
+
+import (
+	"context"
+	"errors"
+
+	"log/slog"
+)
+
+// MultiHandler sends the records to several sinks, e.g. the colored text on the
+// console and the json in a file. Each sink decides its own writer, format, level
+// and colors by its options, and the records are passed to the enabled ones only.
+//
+//	h := NewMultiHandler(
+//		NewTextHandler(WithWriter(os.Stderr)),
+//		NewJsonHandler(WithWriter(file), WithLevel(slog.LevelDebug)),
+//	)
+//	slog.SetDefault(slog.New(h))
+//
+// WithPrefix and WithThemes are propagated to the sinks implementing Handler,
+// so CopyWithPrefix and CopyWithThemes keep working.
+type MultiHandler struct {
+	sinks []slog.Handler
+}
+
+// NewMultiHandler creates a handler of sinks.
+func NewMultiHandler(sinks ...slog.Handler) *MultiHandler {
+	return &MultiHandler{sinks: sinks}
+}
+
+// Enabled reports whether any of the sinks handles records at the given level.
+func (m *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, sink := range m.sinks {
+		if sink.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes a clone of r to every sink enabled at its level,
+// the errors of the sinks are joined.
+func (m *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if !sink.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := sink.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MultiHandler) WithPrefix(prefix string) slog.Handler {
+	return m.with(func(h Handler) slog.Handler { return h.WithPrefix(prefix) })
+}
+
+func (m *MultiHandler) WithThemes(themes Themes) slog.Handler {
+	return m.with(func(h Handler) slog.Handler { return h.WithThemes(themes) })
+}
+
+func (m *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return m
+	}
+	sinks := make([]slog.Handler, len(m.sinks))
+	for i, sink := range m.sinks {
+		sinks[i] = sink.WithAttrs(attrs)
+	}
+	return &MultiHandler{sinks: sinks}
+}
+
+func (m *MultiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return m
+	}
+	sinks := make([]slog.Handler, len(m.sinks))
+	for i, sink := range m.sinks {
+		sinks[i] = sink.WithGroup(name)
+	}
+	return &MultiHandler{sinks: sinks}
+}
+
+// with applies fn to the sinks implementing Handler, the others are kept as they're.
+func (m *MultiHandler) with(fn func(Handler) slog.Handler) *MultiHandler {
+	sinks := make([]slog.Handler, len(m.sinks))
+	for i, sink := range m.sinks {
+		if h, ok := sink.(Handler); ok {
+			sinks[i] = fn(h)
+		} else {
+			sinks[i] = sink
+		}
+	}
+	return &MultiHandler{sinks: sinks}
+}
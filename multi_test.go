@@ -0,0 +1,72 @@
+package shandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+)
+
+func TestMultiHandler(t *testing.T) {
+	var console, file, std bytes.Buffer
+	h := NewMultiHandler(
+		NewTextHandler(WithWriter(&console), WithColorMode(ColorAlways)),
+		NewJsonHandler(WithWriter(&file), WithLevel(slog.LevelDebug)),
+		slog.NewTextHandler(&std, nil),
+	)
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("the handler isn't enabled at the level of the json sink")
+	}
+
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(h))
+	logger := CopyWithPrefix("db")
+	if logger == nil {
+		t.Fatal("CopyWithPrefix returns nil of MultiHandler")
+	}
+	logger = logger.WithGroup("q").With("table", "users")
+	logger.Debug("debug")
+	logger.Info("info", "rows", 2)
+
+	if out := console.String(); strings.Contains(out, "debug") || !strings.Contains(out, "\x1b[") ||
+		!strings.Contains(out, "db") || !strings.Contains(out, "info") {
+		t.Errorf("got console %q", out)
+	}
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 2 || strings.Contains(file.String(), "\x1b[") {
+		t.Fatalf("got file %q", file.String())
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[prefixKey] != "db" || m[slog.MessageKey] != "info" {
+		t.Errorf("got %v", m)
+	}
+	if q, _ := m["q"].(map[string]any); q["table"] != "users" || q["rows"] != 2.0 {
+		t.Errorf("got group %v", m["q"])
+	}
+	if out := std.String(); strings.Contains(out, "debug") || !strings.Contains(out, "q.table=users q.rows=2") {
+		t.Errorf("got std %q", out)
+	}
+}
+
+func TestMultiHandlerErrors(t *testing.T) {
+	errEncode := errors.New("encode")
+	var buf bytes.Buffer
+	h := NewMultiHandler(
+		NewEncoderHandler(EncoderFunc(func(*Buffer, *Entry) error { return errEncode })),
+		NewTextHandler(WithWriter(&buf)),
+	)
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)); !errors.Is(err, errEncode) {
+		t.Errorf("got %v, want %v", err, errEncode)
+	}
+	if !strings.Contains(buf.String(), "msg") {
+		t.Errorf("the record isn't passed to the other sinks: %q", buf.String())
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !race
+
+package shandler
+
+// raceEnabled reports whether the race detector is on, it allocates more.
+const raceEnabled = false
@@ -3,8 +3,11 @@ package shandler
 import (
 	"io"
 	"os"
+	"slices"
+	"sync"
+	"time"
 
-	"github.com/lucasb-eyer/go-colorful"
+	"github.com/muesli/termenv"
 	"log/slog"
 )
 
@@ -43,41 +46,109 @@ func createHandler(json bool, opts ...Option) *baseHandler {
 		w:          os.Stderr,
 		level:      slog.LevelInfo,
 		json:       json,
-		themes:     make(map[ThemeSchema]*Theme, 9),
+		themes:     make(map[ThemeSchema]*Theme, 17),
+		levels:     slices.Clone(defaultLevels),
+		queried:    &backgroundQuery{},
+	}
+	if json {
+		h.timeFormat = time.RFC3339Nano
 	}
 	for _, opt := range opts {
 		opt(h)
 	}
+	h.seq = writeSeqOf(h.w)
 	h.initThemes()
 	return h
 }
 
 func (h *baseHandler) initThemes() {
-	if h.tty = h.isTTY(); !h.tty {
+	defer h.updatePrefixTheme()
+	var profile ColorProfile
+	if h.colored, profile = h.isColored(); !h.colored {
 		return
 	}
+	if h.profile == ProfileAuto {
+		h.profile = profile
+	}
+	if h.profile == ProfileAuto {
+		h.profile = profileOf(termenv.NewOutput(h.w, termenv.WithTTY(true)).ColorProfile())
+		if h.profile == ProfileAscii && (os.Getenv("TERM") != "dumb" || forcedByEnv() || h.colorMode == ColorAlways) {
+			// colors are asked but the terminal isn't recognized, e.g. a pipe,
+			// a dumb terminal keeps no colors unless they're forced by ColorAlways
+			// or the environment
+			h.profile = ProfileANSI
+		}
+	}
+	h.fillThemes()
+	h.profileThemes()
+}
+
+// backgroundQuery is the background of the output queried once by the clones of a handler.
+type backgroundQuery struct {
+	once sync.Once
+	dark bool
+}
 
-	h.themes[ThemeTime] = fillTheme(h.themes[ThemeTime], "#6085b9", "#7d467c", false, true, false)
-	h.themes[ThemeDebug] = fillTheme(h.themes[ThemeDebug], "#4746ff", "#2f81ff", true, false, false)
-	h.themes[ThemeInfo] = fillTheme(h.themes[ThemeInfo], "#009adc", "#00FFD5", true, false, false)
-	h.themes[ThemeWarn] = fillTheme(h.themes[ThemeWarn], "#e16c00", "#ff9c01", true, false, false)
-	h.themes[ThemeError] = fillTheme(h.themes[ThemeError], "#ff000a", "#FF4F86", true, false, false)
-	h.themes[ThemePrefix] = fillTheme(h.themes[ThemePrefix], "#579159", "#008708", true, false, false)
-	h.themes[ThemeCaller] = fillTheme(h.themes[ThemeCaller], "#765ea5", "#2f6e87", false, false, false)
-	h.themes[ThemeKey] = fillTheme(h.themes[ThemeKey], "#7F7F7F", "#7F7F7F", true, false, false)
-	if h.json {
-		h.themes[ThemeBracket] = fillTheme(h.themes[ThemeBracket], "#000000", "#ffffff", true, false, false)
+// isDark reports whether the background of the output is dark. The terminal
+// is queried on the first call unless it's set by WithBackground, the result
+// is shared by the clones.
+func (h *baseHandler) isDark() bool {
+	switch h.background {
+	case BackgroundLight:
+		return false
+	case BackgroundDark:
+		return true
 	}
+	h.queried.once.Do(func() {
+		h.queried.dark = termenv.NewOutput(h.w).HasDarkBackground()
+	})
+	return h.queried.dark
 }
 
-func fillTheme(source *Theme, l, d string, bold, underline, overline bool) *Theme {
-	if source == nil {
-		light, _ := colorful.Hex(l)
-		dark, _ := colorful.Hex(d)
-		source = NewTheme().Foreground(light, dark)
-		source.Bold(bold).Underline(underline).Overline(overline)
+// profileThemes formats the themes by the color profile of the output,
+// the variant of the background is picked when they're rendered.
+func (h *baseHandler) profileThemes() {
+	for schema, theme := range h.themes {
+		h.themes[schema] = theme.profiled(h.profile)
+	}
+	var levels []levelDef
+	for i, def := range h.levels {
+		for j, theme := range []*Theme{def.theme, def.message} {
+			if theme == nil {
+				continue
+			}
+			profiled := theme.profiled(h.profile)
+			if profiled == theme {
+				continue
+			}
+			if levels == nil {
+				levels = slices.Clone(h.levels)
+			}
+			if j == 0 {
+				levels[i].theme = profiled
+			} else {
+				levels[i].message = profiled
+			}
+		}
+	}
+	if levels != nil {
+		h.levels = levels
+	}
+	var rules []StyleRule
+	for i, r := range h.rules {
+		if r.Theme == nil {
+			continue
+		}
+		if theme := r.Theme.profiled(h.profile); theme != r.Theme {
+			if rules == nil {
+				rules = slices.Clone(h.rules)
+			}
+			rules[i].Theme = theme
+		}
+	}
+	if rules != nil {
+		h.rules = rules
 	}
-	return source.Format()
 }
 
 func WithTimeFormat(format string) Option {
@@ -92,12 +163,40 @@ func WithWriter(w io.Writer) Option {
 	}
 }
 
-func WithLevel(level slog.Level) Option {
+// WithLevel sets the min level of the logger, pass a *slog.LevelVar
+// to change it at runtime, see also LevelHandler.
+func WithLevel(level slog.Leveler) Option {
 	return func(cfg *baseHandler) {
+		if level == nil {
+			return
+		}
 		cfg.level = level
 	}
 }
 
+// WithCustomLevel registers level to be rendered as label, records of the levels
+// between two registered ones are rendered as the lower one. If theme is nil,
+// the theme of the default level the custom one falls into is used.
+//
+//	WithCustomLevel(LevelTrace, "TRAC", NewTheme().Foreground(light, dark))
+func WithCustomLevel(level slog.Level, label string, theme *Theme) Option {
+	return func(cfg *baseHandler) {
+		def := defaultLevels[0]
+		for _, d := range defaultLevels {
+			if d.level <= level {
+				def = d
+			}
+		}
+		cfg.addLevel(levelDef{
+			level:  level,
+			label:  label,
+			schema: def.schema,
+			theme:  theme,
+			custom: true,
+		})
+	}
+}
+
 func WithPrefix(prefix string) Option {
 	return func(cfg *baseHandler) {
 		cfg.prefix = prefix
@@ -123,11 +222,27 @@ func WithFullCaller() Option {
 	}
 }
 
+// WithColorProfile forces the color profile of the output instead of detecting it,
+// the colors of the themes are converted to the nearest ones of the profile.
+func WithColorProfile(profile ColorProfile) Option {
+	return func(cfg *baseHandler) {
+		cfg.profile = profile
+	}
+}
+
+// WithBackground sets the background of the output instead of querying the terminal,
+// the colors of the themes are picked from the light or dark variant by it.
+func WithBackground(background BackgroundMode) Option {
+	return func(cfg *baseHandler) {
+		cfg.background = background
+	}
+}
+
 func WithTheme(section ThemeSchema, theme *Theme) Option {
 	return func(cfg *baseHandler) {
 		if theme == nil {
 			return
 		}
-		cfg.themes[section] = theme.Format()
+		cfg.themes[section] = theme
 	}
 }
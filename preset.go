@@ -0,0 +1,253 @@
+package shandler
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Names of the built-in presets, refer to WithPreset.
+const (
+	PresetDefault      = "default"
+	PresetDracula      = "dracula"
+	PresetSolarized    = "solarized"
+	PresetNord         = "nord"
+	PresetMonokai      = "monokai"
+	PresetHighContrast = "high-contrast"
+)
+
+// minHighContrast is the min WCAG contrast ratio of the high-contrast preset
+// against the background, 7:1 is the AAA level of normal text.
+const minHighContrast = 7
+
+// presetStyle is the style of a schema in a preset, the colors are
+// the hex of the variants for light and dark background.
+type presetStyle struct {
+	light, dark string
+	bold        bool
+	faint       bool
+	italic      bool
+	underline   bool
+}
+
+func (s presetStyle) theme() *Theme {
+	light, _ := colorful.Hex(s.light)
+	dark, _ := colorful.Hex(s.dark)
+	return NewTheme().Foreground(light, dark).
+		Bold(s.bold).Faint(s.faint).Italic(s.italic).Underline(s.underline)
+}
+
+type palette map[ThemeSchema]presetStyle
+
+var presets = map[string]palette{
+	PresetDefault: {
+		ThemeTime:       {light: "#6085b9", dark: "#7d467c", underline: true},
+		ThemeDebug:      {light: "#4746ff", dark: "#2f81ff", bold: true},
+		ThemeInfo:       {light: "#009adc", dark: "#00FFD5", bold: true},
+		ThemeWarn:       {light: "#e16c00", dark: "#ff9c01", bold: true},
+		ThemeError:      {light: "#ff000a", dark: "#FF4F86", bold: true},
+		ThemePrefix:     {light: "#579159", dark: "#008708", bold: true},
+		ThemeCaller:     {light: "#765ea5", dark: "#2f6e87"},
+		ThemeKey:        {light: "#7F7F7F", dark: "#7F7F7F", bold: true},
+		ThemeBracket:    {light: "#000000", dark: "#ffffff", bold: true},
+		ThemeString:     {light: "#2e7d32", dark: "#a5d6a7"},
+		ThemeNumber:     {light: "#1565c0", dark: "#82aaff"},
+		ThemeBool:       {light: "#c62828", dark: "#f78c6c"},
+		ThemeNull:       {light: "#7F7F7F", dark: "#7F7F7F"},
+		ThemeTimeValue:  {light: "#6085b9", dark: "#9c8ade"},
+		ThemeDuration:   {light: "#00838f", dark: "#80cbc4"},
+		ThemeErrorValue: {light: "#ff000a", dark: "#FF4F86", bold: true},
+		ThemeAny:        {light: "#5d4037", dark: "#d7ccc8"},
+	},
+	// https://draculatheme.com, the light variant is Alucard.
+	PresetDracula: {
+		ThemeTime:       {light: "#6c664b", dark: "#6272a4"},
+		ThemeDebug:      {light: "#644ac9", dark: "#bd93f9", bold: true},
+		ThemeInfo:       {light: "#036a96", dark: "#8be9fd", bold: true},
+		ThemeWarn:       {light: "#a34d14", dark: "#ffb86c", bold: true},
+		ThemeError:      {light: "#cb3a2a", dark: "#ff5555", bold: true},
+		ThemePrefix:     {light: "#14710a", dark: "#50fa7b", bold: true},
+		ThemeCaller:     {light: "#6c664b", dark: "#6272a4", italic: true},
+		ThemeKey:        {light: "#a3144d", dark: "#ff79c6"},
+		ThemeBracket:    {light: "#1f1f1f", dark: "#f8f8f2", bold: true},
+		ThemeString:     {light: "#846e15", dark: "#f1fa8c"},
+		ThemeNumber:     {light: "#644ac9", dark: "#bd93f9"},
+		ThemeBool:       {light: "#a34d14", dark: "#ffb86c"},
+		ThemeNull:       {light: "#6c664b", dark: "#6272a4"},
+		ThemeTimeValue:  {light: "#036a96", dark: "#8be9fd"},
+		ThemeDuration:   {light: "#14710a", dark: "#50fa7b"},
+		ThemeErrorValue: {light: "#cb3a2a", dark: "#ff5555", bold: true},
+		ThemeAny:        {light: "#1f1f1f", dark: "#f8f8f2"},
+	},
+	// https://ethanschoonover.com/solarized, the accents are shared by both variants.
+	PresetSolarized: {
+		ThemeTime:       {light: "#93a1a1", dark: "#586e75"},
+		ThemeDebug:      {light: "#6c71c4", dark: "#6c71c4", bold: true},
+		ThemeInfo:       {light: "#268bd2", dark: "#268bd2", bold: true},
+		ThemeWarn:       {light: "#b58900", dark: "#b58900", bold: true},
+		ThemeError:      {light: "#dc322f", dark: "#dc322f", bold: true},
+		ThemePrefix:     {light: "#cb4b16", dark: "#cb4b16", bold: true},
+		ThemeCaller:     {light: "#93a1a1", dark: "#586e75", italic: true},
+		ThemeKey:        {light: "#2aa198", dark: "#2aa198"},
+		ThemeBracket:    {light: "#586e75", dark: "#93a1a1", bold: true},
+		ThemeString:     {light: "#859900", dark: "#859900"},
+		ThemeNumber:     {light: "#d33682", dark: "#d33682"},
+		ThemeBool:       {light: "#6c71c4", dark: "#6c71c4"},
+		ThemeNull:       {light: "#93a1a1", dark: "#586e75"},
+		ThemeTimeValue:  {light: "#268bd2", dark: "#268bd2"},
+		ThemeDuration:   {light: "#2aa198", dark: "#2aa198"},
+		ThemeErrorValue: {light: "#dc322f", dark: "#dc322f", bold: true},
+		ThemeAny:        {light: "#657b83", dark: "#839496"},
+	},
+	// https://www.nordtheme.com, the light variant darkens the frost and aurora.
+	PresetNord: {
+		ThemeTime:       {light: "#4c566a", dark: "#4c566a"},
+		ThemeDebug:      {light: "#5e81ac", dark: "#81a1c1", bold: true},
+		ThemeInfo:       {light: "#3b7d8c", dark: "#88c0d0", bold: true},
+		ThemeWarn:       {light: "#b5651d", dark: "#d08770", bold: true},
+		ThemeError:      {light: "#a3414a", dark: "#bf616a", bold: true},
+		ThemePrefix:     {light: "#5f7f45", dark: "#a3be8c", bold: true},
+		ThemeCaller:     {light: "#4c566a", dark: "#616e88", italic: true},
+		ThemeKey:        {light: "#3e6e6d", dark: "#8fbcbb"},
+		ThemeBracket:    {light: "#2e3440", dark: "#eceff4", bold: true},
+		ThemeString:     {light: "#5f7f45", dark: "#a3be8c"},
+		ThemeNumber:     {light: "#8a5f83", dark: "#b48ead"},
+		ThemeBool:       {light: "#8f7425", dark: "#ebcb8b"},
+		ThemeNull:       {light: "#4c566a", dark: "#616e88"},
+		ThemeTimeValue:  {light: "#5e81ac", dark: "#81a1c1"},
+		ThemeDuration:   {light: "#3b7d8c", dark: "#88c0d0"},
+		ThemeErrorValue: {light: "#a3414a", dark: "#bf616a", bold: true},
+		ThemeAny:        {light: "#2e3440", dark: "#d8dee9"},
+	},
+	// Monokai, the light variant darkens the accents.
+	PresetMonokai: {
+		ThemeTime:       {light: "#75715e", dark: "#75715e"},
+		ThemeDebug:      {light: "#684d99", dark: "#ae81ff", bold: true},
+		ThemeInfo:       {light: "#1f8ea3", dark: "#66d9ef", bold: true},
+		ThemeWarn:       {light: "#cf7000", dark: "#fd971f", bold: true},
+		ThemeError:      {light: "#f92672", dark: "#f92672", bold: true},
+		ThemePrefix:     {light: "#6a9a00", dark: "#a6e22e", bold: true},
+		ThemeCaller:     {light: "#75715e", dark: "#75715e", italic: true},
+		ThemeKey:        {light: "#f92672", dark: "#f92672"},
+		ThemeBracket:    {light: "#272822", dark: "#f8f8f2", bold: true},
+		ThemeString:     {light: "#998f2f", dark: "#e6db74"},
+		ThemeNumber:     {light: "#684d99", dark: "#ae81ff"},
+		ThemeBool:       {light: "#684d99", dark: "#ae81ff"},
+		ThemeNull:       {light: "#75715e", dark: "#75715e"},
+		ThemeTimeValue:  {light: "#1f8ea3", dark: "#66d9ef"},
+		ThemeDuration:   {light: "#cf7000", dark: "#fd971f"},
+		ThemeErrorValue: {light: "#f92672", dark: "#f92672", bold: true},
+		ThemeAny:        {light: "#272822", dark: "#f8f8f2"},
+	},
+	// The Okabe-Ito palette, which is distinguishable with color blindness.
+	// The colors are adjusted by highContrast to reach minHighContrast.
+	PresetHighContrast: highContrast(palette{
+		ThemeTime:       {light: "#000000", dark: "#ffffff"},
+		ThemeDebug:      {light: "#0072b2", dark: "#56b4e9", bold: true},
+		ThemeInfo:       {light: "#009e73", dark: "#009e73", bold: true},
+		ThemeWarn:       {light: "#e69f00", dark: "#f0e442", bold: true, underline: true},
+		ThemeError:      {light: "#d55e00", dark: "#d55e00", bold: true, underline: true},
+		ThemePrefix:     {light: "#cc79a7", dark: "#cc79a7", bold: true},
+		ThemeCaller:     {light: "#000000", dark: "#ffffff", italic: true},
+		ThemeKey:        {light: "#0072b2", dark: "#56b4e9"},
+		ThemeBracket:    {light: "#000000", dark: "#ffffff", bold: true},
+		ThemeString:     {light: "#009e73", dark: "#009e73"},
+		ThemeNumber:     {light: "#cc79a7", dark: "#cc79a7"},
+		ThemeBool:       {light: "#e69f00", dark: "#f0e442"},
+		ThemeNull:       {light: "#000000", dark: "#ffffff", italic: true},
+		ThemeTimeValue:  {light: "#0072b2", dark: "#56b4e9"},
+		ThemeDuration:   {light: "#009e73", dark: "#009e73"},
+		ThemeErrorValue: {light: "#d55e00", dark: "#d55e00", bold: true, underline: true},
+		ThemeAny:        {light: "#000000", dark: "#ffffff"},
+	}),
+}
+
+// Presets returns the names of the built-in presets.
+func Presets() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// highContrast adjusts the colors of p to reach minHighContrast
+// against a white background for light and black for dark.
+func highContrast(p palette) palette {
+	white, black := colorful.Color{R: 1, G: 1, B: 1}, colorful.Color{}
+	for schema, style := range p {
+		light, _ := colorful.Hex(style.light)
+		dark, _ := colorful.Hex(style.dark)
+		style.light = ensureContrast(light, white, minHighContrast).Hex()
+		style.dark = ensureContrast(dark, black, minHighContrast).Hex()
+		p[schema] = style
+	}
+	return p
+}
+
+// ensureContrast changes the lightness of c away from background
+// until their contrast ratio reaches min, the hue is kept.
+func ensureContrast(c, background colorful.Color, min float64) colorful.Color {
+	h, chroma, l := c.Hcl()
+	step := 0.01
+	if luminance(background) > 0.5 {
+		step = -step
+	}
+	for contrastRatio(c, background) < min && l >= 0 && l <= 1 {
+		l += step
+		c = colorful.Hcl(h, chroma, l).Clamped()
+	}
+	return c
+}
+
+// luminance returns the relative luminance of c defined by WCAG.
+func luminance(c colorful.Color) float64 {
+	r, g, b := c.LinearRgb()
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// contrastRatio returns the WCAG contrast ratio of a and b, from 1 to 21.
+func contrastRatio(a, b colorful.Color) float64 {
+	la, lb := luminance(a), luminance(b)
+	return (math.Max(la, lb) + 0.05) / (math.Min(la, lb) + 0.05)
+}
+
+// fillThemes sets the theme of every schema which has none from the preset.
+func (h *baseHandler) fillThemes() {
+	p, ok := presets[h.preset]
+	if !ok {
+		p = presets[PresetDefault]
+	}
+	for schema, style := range p {
+		if h.themes[schema] == nil {
+			h.themes[schema] = style.theme()
+		}
+	}
+}
+
+// ParsePreset returns the name of the built-in preset of s,
+// e.g. from a configuration, it's an error if there's none.
+func ParsePreset(s string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if _, ok := presets[name]; !ok {
+		return "", fmt.Errorf("shandler: unknown preset %q, expected one of %s", s, strings.Join(Presets(), ", "))
+	}
+	return name, nil
+}
+
+// WithPreset fills the themes from the built-in preset name, see Presets.
+// The themes set by WithTheme take precedence regardless of the order.
+// It panics if name is unknown, validate the names from the outside by ParsePreset.
+func WithPreset(name string) Option {
+	preset, err := ParsePreset(name)
+	if err != nil {
+		panic(err)
+	}
+	return func(cfg *baseHandler) {
+		cfg.preset = preset
+	}
+}
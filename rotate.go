@@ -0,0 +1,375 @@
+package shandler
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rotateTimeFormat is the timestamp in the names of the files, it sorts by time.
+	rotateTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+)
+
+// RotateInterval rotates the file by time, refer to WithRotateInterval.
+type RotateInterval int
+
+const (
+	RotateNever RotateInterval = iota
+	RotateHourly
+	RotateDaily
+)
+
+// RotatingFile is a writer of the log files rotated by size, time or signals,
+// pass it to WithWriter. The records are written to the files named by path
+// with the time they're created, e.g. app-2024-01-02T03-04-05.000.log of
+// app.log, and path is a symlink to the current one where it's supported.
+// The rotated files are compressed and removed by the options in background.
+//
+//	w, err := NewRotatingFile("logs/app.log", WithMaxSize(100<<20), WithRotateInterval(RotateDaily),
+//		WithCompress(), WithMaxBackups(10), WithRotateSignal())
+//	defer w.Close()
+//
+// A RotatingFile is safe for concurrent use, the rotations don't block the
+// writes for longer than opening the new file.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	interval   RotateInterval
+	compress   bool
+	maxAge     time.Duration
+	maxBackups int
+	signals    []os.Signal
+
+	mu   sync.Mutex
+	file *os.File
+	name string // the name of the current file, it's kept after Close
+	size int64
+	next time.Time // the time of the next rotation by interval
+	err  error     // the error of closing a rotated file, it's reported by Rotate and Close
+
+	now     func() time.Time
+	sig     chan os.Signal
+	millMu  sync.Mutex // serializes compressing and removing the backups
+	pending sync.WaitGroup
+}
+
+// RotateOption configures a RotatingFile.
+type RotateOption func(*RotatingFile)
+
+// WithMaxSize rotates the file before it exceeds size bytes.
+func WithMaxSize(size int64) RotateOption {
+	return func(r *RotatingFile) {
+		r.maxSize = size
+	}
+}
+
+// WithRotateInterval rotates the file at the start of every hour or day of the local time.
+func WithRotateInterval(interval RotateInterval) RotateOption {
+	return func(r *RotatingFile) {
+		r.interval = interval
+	}
+}
+
+// WithRotateSignal rotates the file when one of sigs is received, default is SIGHUP.
+// It lets logrotate or an operator trigger the rotation.
+func WithRotateSignal(sigs ...os.Signal) RotateOption {
+	return func(r *RotatingFile) {
+		if len(sigs) == 0 {
+			sigs = rotateSignals
+		}
+		r.signals = sigs
+	}
+}
+
+// WithCompress compresses the rotated files with gzip.
+func WithCompress() RotateOption {
+	return func(r *RotatingFile) {
+		r.compress = true
+	}
+}
+
+// WithMaxAge removes the rotated files older than age.
+func WithMaxAge(age time.Duration) RotateOption {
+	return func(r *RotatingFile) {
+		r.maxAge = age
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files, the oldest ones are removed.
+func WithMaxBackups(n int) RotateOption {
+	return func(r *RotatingFile) {
+		r.maxBackups = n
+	}
+}
+
+// NewRotatingFile opens a new file of path, the directory is created if it doesn't exist.
+func NewRotatingFile(path string, opts ...RotateOption) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, now: time.Now}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	if len(r.signals) > 0 {
+		r.sig = make(chan os.Signal, 1)
+		signal.Notify(r.sig, r.signals...)
+		go r.watchSignals(r.sig)
+	}
+	return r, nil
+}
+
+// Write writes p to the current file, it's rotated first if p doesn't fit
+// in the max size or the interval is over.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return 0, ErrWriterClosed
+	}
+	if r.needRotate(len(p)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file and opens a new one, it reports the error
+// of closing the rotated files since the last report too.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ErrWriterClosed
+	}
+	if err := r.rotate(); err != nil {
+		return err
+	}
+	err := r.err
+	r.err = nil
+	return err
+}
+
+// Close closes the current file and waits for the background
+// compression and removal of the rotated files. It reports the error
+// of closing the rotated files too, see Rotate.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	if r.sig != nil {
+		signal.Stop(r.sig)
+		close(r.sig)
+		r.sig = nil
+	}
+	err := r.err
+	r.err = nil
+	if r.file != nil {
+		err = errors.Join(err, r.file.Close())
+		r.file = nil
+	}
+	r.mu.Unlock()
+	r.pending.Wait()
+	return err
+}
+
+// Name returns the name of the current file.
+func (r *RotatingFile) Name() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ""
+	}
+	return r.file.Name()
+}
+
+func (r *RotatingFile) watchSignals(sig chan os.Signal) {
+	for range sig {
+		_ = r.Rotate()
+	}
+}
+
+func (r *RotatingFile) needRotate(n int) bool {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(n) > r.maxSize {
+		return true
+	}
+	return r.interval != RotateNever && !r.now().Before(r.next)
+}
+
+// rotate switches to a new file and mills the old one in background, r.mu must be locked.
+// It fails only if the new file isn't opened, the error of closing the old one is kept
+// in r.err as the writes go to the new one anyway.
+func (r *RotatingFile) rotate() error {
+	old := r.file
+	if err := r.open(); err != nil {
+		return err
+	}
+	if err := old.Close(); err != nil {
+		r.err = fmt.Errorf("shandler: rotate %s: %w", old.Name(), err)
+	}
+
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.mill(old.Name())
+	}()
+	return nil
+}
+
+// open opens a new file named by the current time and points the symlink to it.
+func (r *RotatingFile) open() error {
+	now := r.now()
+	dir, base := filepath.Split(r.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+
+	var name string
+	for t := now; ; t = t.Add(time.Millisecond) {
+		// a name is never reused, e.g. by the rotations within a millisecond
+		name = filepath.Join(dir, prefix+t.Format(rotateTimeFormat)+ext)
+		if _, err := os.Lstat(name); errors.Is(err, fs.ErrNotExist) {
+			if _, err = os.Lstat(name + compressSuffix); errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+		}
+	}
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	r.file, r.name, r.size = file, name, 0
+	r.next = nextRotation(now, r.interval)
+	r.link(filepath.Base(name))
+	return nil
+}
+
+// link points the symlink of path to name, a regular file of path is left alone.
+// The errors are ignored as symlinks are not supported everywhere.
+func (r *RotatingFile) link(name string) {
+	if info, err := os.Lstat(r.path); err == nil && info.Mode()&fs.ModeSymlink == 0 {
+		return
+	}
+	tmp := r.path + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(name, tmp); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		_ = os.Remove(tmp)
+	}
+}
+
+// nextRotation returns the start of the next hour or day after t.
+func nextRotation(t time.Time, interval RotateInterval) time.Time {
+	switch interval {
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+// mill compresses the rotated file and removes the backups beyond the retention.
+func (r *RotatingFile) mill(rotated string) {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+	if r.compress {
+		_ = compressFile(rotated)
+	}
+	if r.maxAge <= 0 && r.maxBackups <= 0 {
+		return
+	}
+
+	backups := r.backups()
+	cutoff := r.now().Add(-r.maxAge)
+	for i, backup := range backups {
+		// the backups are sorted from the newest
+		tooMany := r.maxBackups > 0 && i >= r.maxBackups
+		tooOld := r.maxAge > 0 && backup.ModTime().Before(cutoff)
+		if tooMany || tooOld {
+			_ = os.Remove(filepath.Join(filepath.Dir(r.path), backup.Name()))
+		}
+	}
+}
+
+// backups returns the rotated files sorted from the newest, the current one excluded.
+func (r *RotatingFile) backups() []fs.FileInfo {
+	dir, base := filepath.Split(r.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	current := filepath.Base(r.name)
+	r.mu.Unlock()
+	var backups []fs.FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == current || !entry.Type().IsRegular() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, compressSuffix), ext)[len(prefix):]
+		if _, err := time.Parse(rotateTimeFormat, stamp); err != nil {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			backups = append(backups, info)
+		}
+	}
+	slices.SortFunc(backups, func(a, b fs.FileInfo) int {
+		return strings.Compare(b.Name(), a.Name())
+	})
+	return backups
+}
+
+// compressFile compresses name to name.gz and removes name.
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(dst.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,8 @@
+//go:build windows || plan9 || js || wasip1
+
+package shandler
+
+import "os"
+
+// rotateSignals are the default signals of WithRotateSignal, there's no SIGHUP.
+var rotateSignals []os.Signal
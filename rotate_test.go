@@ -0,0 +1,149 @@
+package shandler
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	r, err := NewRotatingFile(path, WithMaxSize(10), WithCompress(), WithMaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Now()
+	r.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	for _, s := range []string{"1234567\n", "a\n", "b\n", "c\n", "12345678\n", "d\n"} {
+		if _, err := r.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current := r.Name()
+	if target, err := os.Readlink(path); err != nil || target != filepath.Base(current) {
+		t.Errorf("got symlink %q %v, want %q", target, err, filepath.Base(current))
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("e\n")); err != ErrWriterClosed {
+		t.Errorf("got %v, want ErrWriterClosed", err)
+	}
+
+	// 1234567 a | b c 12345678 | d, the oldest backup is removed
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	if len(backups) != 2 {
+		t.Fatalf("got backups %q, want 2", backups)
+	}
+	for i, want := range []string{"b\nc\n", "12345678\n"} {
+		if got := readGzip(t, backups[i]); got != want {
+			t.Errorf("backup %d: got %q, want %q", i, got, want)
+		}
+	}
+	if data, _ := os.ReadFile(current); string(data) != "d\n" {
+		t.Errorf("got current %q, want %q", data, "d\n")
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRotatingFile(filepath.Join(dir, "app"), WithRotateInterval(RotateHourly), WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if next := nextRotation(time.Date(2024, 1, 31, 23, 30, 0, 0, time.UTC), RotateDaily); !next.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got next daily %s", next)
+	}
+
+	first := r.Name()
+	_, _ = r.Write([]byte("a\n"))
+	old := time.Now().Add(-2 * time.Hour)
+	_ = os.Chtimes(first, old, old)
+	r.now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, _ = r.Write([]byte("b\n"))
+	if r.Name() == first {
+		t.Fatal("the file isn't rotated by the interval")
+	}
+
+	r.pending.Wait()
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("the backup older than max age isn't removed: %v", err)
+	}
+}
+
+func TestRotatingFileSignal(t *testing.T) {
+	if len(rotateSignals) == 0 {
+		t.Skip("no rotate signal")
+	}
+	r, err := NewRotatingFile(filepath.Join(t.TempDir(), "app.log"), WithRotateSignal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	first := r.Name()
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(rotateSignals[0]); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(time.Second); r.Name() == first; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the file isn't rotated by the signal")
+		}
+	}
+}
+
+func readGzip(t *testing.T, name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if _, err := io.Copy(&sb, gz); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestRotatingFileCloseError(t *testing.T) {
+	r, err := NewRotatingFile(filepath.Join(t.TempDir(), "app.log"), WithMaxSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Now()
+	r.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	// the rotated file fails to close, the write goes to the new file anyway
+	first := r.Name()
+	_, _ = r.Write([]byte("abc\n"))
+	_ = r.file.Close()
+	if _, err := r.Write([]byte("def\n")); err != nil {
+		t.Fatalf("got %v, want no error of the write", err)
+	}
+	if r.Name() == first {
+		t.Fatal("want a new file")
+	}
+	if data, _ := os.ReadFile(r.Name()); string(data) != "def\n" {
+		t.Errorf("got %q, want %q", data, "def\n")
+	}
+	if err := r.Close(); err == nil || !strings.Contains(err.Error(), "shandler: rotate "+first) {
+		t.Errorf("got %v, want the error of closing %s", err, first)
+	}
+}
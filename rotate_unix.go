@@ -0,0 +1,11 @@
+//go:build !windows && !plan9 && !js && !wasip1
+
+package shandler
+
+import (
+	"os"
+	"syscall"
+)
+
+// rotateSignals are the default signals of WithRotateSignal.
+var rotateSignals = []os.Signal{syscall.SIGHUP}
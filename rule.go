@@ -0,0 +1,159 @@
+package shandler
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"log/slog"
+)
+
+// ruleWildcard at the end of StyleRule.Key matches any suffix.
+const ruleWildcard = '*'
+
+// Predicate reports whether the resolved value of an attr is matched.
+type Predicate func(v slog.Value) bool
+
+// StyleRule styles the values of the attrs matched by Key and When with Theme,
+// it takes precedence over the themes of the value kinds. It's only used by
+// the text handler.
+//
+//	StyleRule{Key: "status", When: Between(500, 599), Theme: red}
+//	StyleRule{Key: "http.*", When: Kind(slog.KindDuration), Theme: bold}
+type StyleRule struct {
+	// Key is the key with the group path like "http.status", it matches
+	// every key if empty or the keys starting with it if it ends with '*'.
+	Key string
+
+	// When matches every value if nil.
+	When Predicate
+
+	Theme *Theme
+}
+
+func (r StyleRule) match(key string, v slog.Value) bool {
+	if r.Key != "" && r.Key != key {
+		prefix, ok := strings.CutSuffix(r.Key, string(ruleWildcard))
+		if !ok || !strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	return r.When == nil || r.When(v)
+}
+
+// matchRule returns the theme of the first rule matching the attr, nil if none.
+func (h *baseHandler) matchRule(key string, v slog.Value) *Theme {
+	for _, r := range h.rules {
+		if r.match(key, v) {
+			return r.Theme
+		}
+	}
+	return nil
+}
+
+// withRules returns a clone whose rules are rules followed by the ones of h,
+// so the rules added later take precedence.
+func (h *baseHandler) withRules(rules []StyleRule) *baseHandler {
+	h2 := h.clone()
+	h2.rules = append(slices.Clone(rules), h.rules...)
+	h2.initThemes()
+	return h2
+}
+
+// WithRules adds the style rules of the text handler, refer to StyleRule.
+// The rules are checked in order, the first matched one wins.
+func WithRules(rules ...StyleRule) Option {
+	return func(cfg *baseHandler) {
+		cfg.rules = append(cfg.rules, rules...)
+	}
+}
+
+// Kind matches the values of kind.
+func Kind(kind slog.Kind) Predicate {
+	return func(v slog.Value) bool {
+		return v.Kind() == kind
+	}
+}
+
+// Equal matches the values equal to value.
+func Equal(value any) Predicate {
+	want := slog.AnyValue(value)
+	return func(v slog.Value) bool {
+		return v.Equal(want)
+	}
+}
+
+// Between matches the numbers from min to max inclusive.
+func Between(min, max float64) Predicate {
+	return func(v slog.Value) bool {
+		n, ok := number(v)
+		return ok && n >= min && n <= max
+	}
+}
+
+// AtLeast matches the numbers greater than or equal to min.
+func AtLeast(min float64) Predicate {
+	return func(v slog.Value) bool {
+		n, ok := number(v)
+		return ok && n >= min
+	}
+}
+
+// LongerThan matches the durations longer than d.
+func LongerThan(d time.Duration) Predicate {
+	return func(v slog.Value) bool {
+		return v.Kind() == slog.KindDuration && v.Duration() > d
+	}
+}
+
+// IsError matches the error values.
+func IsError() Predicate {
+	return func(v slog.Value) bool {
+		_, ok := v.Any().(error)
+		return ok
+	}
+}
+
+// And matches the values matched by all of predicates.
+func And(predicates ...Predicate) Predicate {
+	return func(v slog.Value) bool {
+		for _, p := range predicates {
+			if !p(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches the values matched by any of predicates.
+func Or(predicates ...Predicate) Predicate {
+	return func(v slog.Value) bool {
+		for _, p := range predicates {
+			if p(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches the values not matched by p.
+func Not(p Predicate) Predicate {
+	return func(v slog.Value) bool {
+		return !p(v)
+	}
+}
+
+// number returns the value of the numeric kinds as float64.
+func number(v slog.Value) (float64, bool) {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return float64(v.Int64()), true
+	case slog.KindUint64:
+		return float64(v.Uint64()), true
+	case slog.KindFloat64:
+		return v.Float64(), true
+	}
+	return 0, false
+}
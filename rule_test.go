@@ -0,0 +1,79 @@
+package shandler
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+)
+
+func TestStyleRules(t *testing.T) {
+	red, yellow, bold := NewTheme().Reverse(), NewTheme().Underline(), NewTheme().Bold()
+	var buf bytes.Buffer
+	h := NewTextHandler(
+		WithWriter(&buf),
+		WithColorMode(ColorAlways),
+		WithColorProfile(ProfileAscii),
+		WithRules(
+			StyleRule{Key: "err", Theme: red},
+			StyleRule{Key: "http.status", When: AtLeast(500), Theme: red},
+			StyleRule{Key: "http.status", When: Between(400, 499), Theme: yellow},
+			StyleRule{Key: "http.*", When: And(Kind(slog.KindDuration), LongerThan(time.Second)), Theme: bold},
+		),
+	)
+	logger := slog.New(h.WithThemes(Themes{ThemeKey: NewTheme(), ThemeNumber: NewTheme(), ThemeDuration: NewTheme()}))
+
+	tests := []struct {
+		args []any
+		want string
+	}{
+		{[]any{"err", "x"}, "err=\x1b[7mx\x1b[0m"},
+		{[]any{slog.Group("http", "status", 503)}, "http.status=\x1b[7m503\x1b[0m"},
+		{[]any{slog.Group("http", "status", 404)}, "http.status=\x1b[4m404\x1b[0m"},
+		{[]any{slog.Group("http", "status", 200)}, "http.status=200"},
+		{[]any{"status", 503}, "status=503"},
+		{[]any{slog.Group("http", "latency", 2*time.Second)}, "http.latency=\x1b[1m2s\x1b[0m"},
+		{[]any{slog.Group("http", "latency", time.Millisecond)}, "http.latency=1ms"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		logger.Info("", tt.args...)
+		if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, " "+tt.want) {
+			t.Errorf("got %q, want suffix %q", got, tt.want)
+		}
+	}
+
+	// the rules added by cloning take precedence
+	buf.Reset()
+	h2 := logger.Handler().(*TextHandler).WithRules(StyleRule{Key: "err", When: IsError(), Theme: bold})
+	slog.New(h2).Info("", "err", errors.New("x"), "err", "y")
+	if got, want := buf.String(), " err=\x1b[1mx\x1b[0m err=\x1b[7my\x1b[0m\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, want suffix %q", got, want)
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	tests := []struct {
+		p    Predicate
+		v    any
+		want bool
+	}{
+		{Between(1, 2), 1.5, true},
+		{Between(1, 2), uint64(3), false},
+		{Between(1, 2), "1.5", false},
+		{AtLeast(500), 500, true},
+		{LongerThan(time.Second), time.Second, false},
+		{Equal("ok"), "ok", true},
+		{Or(Equal(1), Equal(2)), 2, true},
+		{Not(Kind(slog.KindString)), 1, true},
+		{IsError(), errors.New("x"), true},
+	}
+	for i, tt := range tests {
+		if got := tt.p(slog.AnyValue(tt.v)); got != tt.want {
+			t.Errorf("%d: got %t, want %t", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package shandler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"log/slog"
+)
+
+// SampleSummaryMessage is the message of the records summarizing the dropped ones,
+// refer to WithSampleSummary.
+const SampleSummaryMessage = "records dropped by sampling"
+
+// SamplingHandler samples the records of the wrapped handler like zap. The records
+// are counted by their level and message in every tick, the first ones of each
+// tick are handled and then every thereafter-th, the others are dropped.
+//
+//	// at most 100 records per second of a message, then 1 in 100
+//	h := NewSamplingHandler(NewTextHandler(), time.Second, 100, 100)
+//
+// The counters are shared by the handlers derived from h, e.g. by With and
+// CopyWithPrefix, so the message logged by several loggers is sampled once.
+type SamplingHandler struct {
+	h slog.Handler
+	s *sampler
+}
+
+// SampleOption configures a SamplingHandler.
+type SampleOption func(*sampler)
+
+// WithSampleSummary handles a record for every level and message which had
+// dropped records in the previous tick, with the message SampleSummaryMessage and
+// the attrs "sampled" of the message and "dropped" of the count. The summaries are
+// handled along with the first record after the tick as there's no timer, each one
+// by the handler which dropped the last record of it, e.g. with its prefix.
+func WithSampleSummary() SampleOption {
+	return func(s *sampler) {
+		s.summary = true
+	}
+}
+
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+type sampleCounter struct {
+	n       uint64
+	dropped uint64
+	h       slog.Handler // the handler which dropped the last record
+}
+
+// sampleSummary is the summary of the dropped records to be handled by h.
+type sampleSummary struct {
+	h slog.Handler
+	r slog.Record
+}
+
+// sampler is the state of the sampling shared by the clones of a SamplingHandler.
+type sampler struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+	summary    bool
+	now        func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time // the start of the current tick
+	counts  map[sampleKey]*sampleCounter
+	dropped atomic.Uint64
+}
+
+// NewSamplingHandler wraps h, in every tick it handles the first records of each level
+// and message and every thereafter-th of the rest, thereafter 0 drops all the rest.
+func NewSamplingHandler(h slog.Handler, tick time.Duration, first, thereafter int, opts ...SampleOption) *SamplingHandler {
+	s := &sampler{
+		tick:       tick,
+		first:      uint64(max(first, 0)),
+		thereafter: uint64(max(thereafter, 0)),
+		now:        time.Now,
+		counts:     make(map[sampleKey]*sampleCounter),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return &SamplingHandler{h: h, s: s}
+}
+
+// Dropped returns the number of the records dropped so far.
+func (s *SamplingHandler) Dropped() uint64 {
+	return s.s.dropped.Load()
+}
+
+func (s *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.h.Enabled(ctx, level)
+}
+
+// Handle passes r to the wrapped handler if it's sampled, preceded by
+// the summaries of the previous tick if WithSampleSummary.
+func (s *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	keep, summaries := s.s.sample(s.h, r.Level, r.Message)
+	var errs []error
+	for _, summary := range summaries {
+		if !summary.h.Enabled(ctx, summary.r.Level) {
+			continue
+		}
+		if err := summary.h.Handle(ctx, summary.r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if keep {
+		if err := s.h.Handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithPrefix sets the prefix of the wrapped handler, s is returned
+// if the wrapped handler isn't a Handler, so the prefix is dropped.
+func (s *SamplingHandler) WithPrefix(prefix string) slog.Handler {
+	if h, ok := s.h.(Handler); ok {
+		return &SamplingHandler{h: h.WithPrefix(prefix), s: s.s}
+	}
+	return s
+}
+
+// WithThemes sets the themes of the wrapped handler, s is returned
+// if the wrapped handler isn't a Handler, so the themes are dropped.
+func (s *SamplingHandler) WithThemes(themes Themes) slog.Handler {
+	if h, ok := s.h.(Handler); ok {
+		return &SamplingHandler{h: h.WithThemes(themes), s: s.s}
+	}
+	return s
+}
+
+func (s *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{h: s.h.WithAttrs(attrs), s: s.s}
+}
+
+func (s *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{h: s.h.WithGroup(name), s: s.s}
+}
+
+// sample counts the record of level and msg handled by h and reports whether
+// it's kept, the summaries of the previous tick are returned if it's over.
+func (s *sampler) sample(h slog.Handler, level slog.Level, msg string) (keep bool, summaries []sampleSummary) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.start) >= s.tick {
+		summaries = s.summarize(now)
+		s.start = now
+		clear(s.counts)
+	}
+
+	key := sampleKey{level: level, msg: msg}
+	c := s.counts[key]
+	if c == nil {
+		c = &sampleCounter{}
+		s.counts[key] = c
+	}
+	c.n++
+	if c.n <= s.first || (s.thereafter > 0 && (c.n-s.first)%s.thereafter == 0) {
+		return true, summaries
+	}
+	c.dropped++
+	c.h = h
+	s.dropped.Add(1)
+	return false, summaries
+}
+
+// summarize returns the summaries of the counters with dropped records, s.mu must be locked.
+func (s *sampler) summarize(now time.Time) []sampleSummary {
+	if !s.summary {
+		return nil
+	}
+	keys := make([]sampleKey, 0, len(s.counts))
+	for key, c := range s.counts {
+		if c.dropped > 0 {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b sampleKey) int {
+		if a.level != b.level {
+			return int(a.level - b.level)
+		}
+		return strings.Compare(a.msg, b.msg)
+	})
+
+	summaries := make([]sampleSummary, len(keys))
+	for i, key := range keys {
+		c := s.counts[key]
+		r := slog.NewRecord(now, key.level, SampleSummaryMessage, 0)
+		r.AddAttrs(slog.String("sampled", key.msg), slog.Uint64("dropped", c.dropped))
+		summaries[i] = sampleSummary{h: c.h, r: r}
+	}
+	return summaries
+}
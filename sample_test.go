@@ -0,0 +1,65 @@
+package shandler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"log/slog"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewSamplingHandler(NewTextHandler(WithWriter(&buf), WithTimeFormat("-")), time.Second, 2, 3, WithSampleSummary())
+	clock := time.Now()
+	h.s.now = func() time.Time { return clock }
+
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+	slog.SetDefault(slog.New(h))
+	logger := slog.New(h)
+	db := CopyWithPrefix("db")
+	for i := 1; i <= 10; i++ {
+		logger.Info("hot", "i", i)
+	}
+	db.Warn("hot")
+	if got, want := strings.Count(buf.String(), "hot"), 5; got != want {
+		t.Errorf("got %d records, want %d: %q", got, want, buf.String())
+	}
+	for _, want := range []string{"i=1\n", "i=2\n", "i=5\n", "i=8\n", "db"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%q isn't sampled: %q", want, buf.String())
+		}
+	}
+	if got := h.Dropped(); got != 6 {
+		t.Errorf("got dropped %d, want 6", got)
+	}
+
+	buf.Reset()
+	clock = clock.Add(time.Second)
+	db.Info("hot")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], SampleSummaryMessage) ||
+		!strings.Contains(lines[0], "sampled=hot dropped=6") || !strings.Contains(lines[1], "[db]: hot") {
+		t.Errorf("got %q, want the summary and the record", lines)
+	}
+	// the summary is handled by the logger which dropped the records
+	if strings.Contains(lines[0], "db") {
+		t.Errorf("got summary %q, want it without the prefix of db", lines[0])
+	}
+
+	buf.Reset()
+	clock = clock.Add(time.Second)
+	logger.Info("hot")
+	if strings.Contains(buf.String(), SampleSummaryMessage) {
+		t.Errorf("got summary of the tick without drops: %q", buf.String())
+	}
+}
+
+func TestSamplingHandlerNotHandler(t *testing.T) {
+	h := NewSamplingHandler(slog.NewTextHandler(new(bytes.Buffer), nil), time.Second, 1, 0)
+	if got := h.WithPrefix("db"); got != h {
+		t.Errorf("got %T, want the handler itself", got)
+	}
+}
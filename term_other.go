@@ -0,0 +1,10 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package shandler
+
+import "io"
+
+// terminalWidth returns 0 as the columns of the terminal are unknown.
+func terminalWidth(io.Writer) int {
+	return 0
+}
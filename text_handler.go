@@ -1,9 +1,7 @@
 package shandler
 
 import (
-	"runtime"
 	"strconv"
-	"strings"
 
 	"log/slog"
 )
@@ -11,7 +9,6 @@ import (
 const (
 	textComponentSep = '='
 	textAttrSep      = ' '
-	callerSep        = '/'
 )
 
 type TextHandler struct {
@@ -30,43 +27,75 @@ func (t *TextHandler) WithThemes(themes Themes) slog.Handler {
 	return &TextHandler{t.withThemes(themes)}
 }
 
+// WithRules returns a handler whose style rules are rules followed by the ones
+// of t, so they take precedence, refer to StyleRule. The attrs added
+// by WithAttrs before are already rendered, they're not restyled.
+func (t *TextHandler) WithRules(rules ...StyleRule) slog.Handler {
+	return &TextHandler{t.withRules(rules)}
+}
+
+func (t *TextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &TextHandler{t.withAttrs(attrs)}
+}
+
+func (t *TextHandler) WithGroup(name string) slog.Handler {
+	return &TextHandler{t.withGroup(name)}
+}
+
 type textBuilder struct {
 	*baseBuilder
+	layout bool // the components are separated by the layout
 }
 
 func (b *textBuilder) start() {}
 func (b *textBuilder) close() {}
 
+// writeSep separates the component about to be written from the previous one.
+func (b *textBuilder) writeSep() {
+	if !b.layout && len(*b.buf) > 0 {
+		b.buf.WriteByte(textAttrSep)
+	}
+}
+
 // appendTime If r.Time is the zero time, ignore the time.
 func (b *textBuilder) appendTime() {
 	if b.r.Time.IsZero() {
 		return
 	}
 
-	b.h.WriteColorful(ThemeTime, b.buf, b.r.Time.Format(b.h.timeFormat))
+	a, ok := b.replaceBuiltin(slog.Time(slog.TimeKey, b.r.Time))
+	if !ok {
+		return
+	}
+	b.writeSep()
+	if a.Value.Kind() == slog.KindTime {
+		b.h.WriteColorful(ThemeTime, b.buf, a.Value.Time().Format(b.h.timeFormat))
+	} else {
+		b.h.WriteColorful(ThemeTime, b.buf, a.Value.String())
+	}
 }
 
 func (b *textBuilder) appendLevel() {
-	if !b.r.Time.IsZero() {
-		b.buf.WriteByte(textAttrSep)
+	def := b.h.findLevel(b.r.Level)
+	level := b.h.formatLevel(b.r.Level, def)
+	if b.h.replacer != nil {
+		a, ok := b.replaceBuiltin(slog.Any(slog.LevelKey, b.r.Level))
+		if !ok {
+			return
+		}
+		if l, isLevel := a.Value.Any().(slog.Level); isLevel {
+			def = b.h.findLevel(l)
+			level = b.h.formatLevel(l, def)
+		} else {
+			level = a.Value.String()
+		}
 	}
-	var level string
-	var section ThemeSchema
-	switch {
-	case b.r.Level < slog.LevelInfo:
-		section = ThemeDebug
-		level = "DBUG"
-	case b.r.Level < slog.LevelWarn:
-		section = ThemeInfo
-		level = "INFO"
-	case b.r.Level < slog.LevelError:
-		section = ThemeWarn
-		level = "WARN"
-	default:
-		section = ThemeError
-		level = "ERRO"
+	b.writeSep()
+	if b.h.levelBadge {
+		b.h.writeBadge(b.h.levelTheme(def), b.buf, level)
+	} else {
+		b.h.writeTheme(b.h.levelTheme(def), b.buf, level)
 	}
-	b.h.WriteColorful(section, b.buf, level)
 }
 
 // appendCaller If r.PC is zero or disabled caller, ignore it.
@@ -75,66 +104,84 @@ func (b *textBuilder) appendCaller() {
 		return
 	}
 
-	b.buf.WriteByte(textAttrSep)
-	fs := runtime.CallersFrames([]uintptr{b.r.PC})
-	f, _ := fs.Next()
-	caller := f.Function
-	if !b.h.fullCaller {
-		var founded int
-		idx := strings.LastIndexFunc(caller, func(r rune) bool {
-			if r == callerSep {
-				founded++
-			}
-			if founded == 2 {
-				return true
-			}
-			return false
-		})
-		caller = caller[idx+1:]
-	}
-	caller = "<" + caller + ":" + strconv.Itoa(f.Line) + ">"
+	src := b.source()
+	caller := "<" + b.caller(src) + ">"
+	if b.h.replacer != nil {
+		a, ok := b.replaceBuiltin(slog.Any(slog.SourceKey, &src))
+		if !ok {
+			return
+		}
+		if s, isSource := a.Value.Any().(*slog.Source); isSource {
+			caller = "<" + b.caller(*s) + ">"
+		} else {
+			caller = a.Value.String()
+		}
+	}
+	b.writeSep()
 	b.h.WriteColorful(ThemeCaller, b.buf, caller)
 }
 
 func (b *textBuilder) appendPrefix() {
-	var prefix string
 	if b.h.prefix == "" {
-		prefix = ""
-	} else {
-		prefix = "[" + b.h.prefix + "]:"
+		return
 	}
 
-	b.buf.WriteByte(textAttrSep)
-	b.h.WriteColorful(ThemePrefix, b.buf, prefix)
+	b.writeSep()
+	b.h.writeTheme(b.h.prefixTheme(), b.buf, "["+b.h.prefix+"]:")
 }
 
 func (b *textBuilder) appendMessage() {
-	if b.r.Message == "" {
+	msg := b.r.Message
+	if b.h.replacer != nil {
+		a, ok := b.replaceBuiltin(slog.String(slog.MessageKey, msg))
+		if !ok {
+			return
+		}
+		msg = a.Value.String()
+	}
+	if msg == "" {
 		return
 	}
 
-	b.buf.WriteByte(textAttrSep)
-	b.buf.WriteString(b.r.Message)
+	b.writeSep()
+	// quoted like the values, so the attrs are told apart from it
+	b.h.writeTheme(b.h.findLevel(b.r.Level).message, b.buf, b.quote(msg))
 }
 
 func (b *textBuilder) appendAttrs() {
-	if len(b.h.preformatted) > 0 {
-		b.buf.WriteByte(textAttrSep)
-		_, _ = b.buf.Write(b.h.preformatted)
-	}
+	_, _ = b.buf.Write(b.h.preformatted)
 
 	b.prefix = NewBuffer()
 	defer b.prefix.Free()
 	b.prefix.WriteString(b.h.groupPrefix)
-	for _, name := range b.h.groups[b.h.nOpenGroups:] {
-		b.openGroup(name)
-	}
+	b.openGroups()
 	b.r.Attrs(func(a slog.Attr) bool {
 		b.appendAttr(a)
 		return true
 	})
 }
 
+// preformat renders attrs into the handler's preformatted buffer and
+// records the groups opened along the way, so Handle doesn't open them again.
+func (b *textBuilder) preformat(attrs []slog.Attr) {
+	defer b.freeGroups()
+	b.prefix = NewBuffer()
+	defer b.prefix.Free()
+	b.prefix.WriteString(b.h.groupPrefix)
+	b.openGroups()
+	for _, a := range attrs {
+		b.appendAttr(a)
+	}
+	b.h.groupPrefix = b.prefix.String()
+	b.h.nOpenGroups = len(b.h.groups)
+}
+
+func (b *textBuilder) openGroups() {
+	for _, name := range b.h.groups[b.h.nOpenGroups:] {
+		b.openGroup(name)
+	}
+}
+
 func (b *textBuilder) openGroup(name string) {
 	b.prefix.WriteString(name)
 	b.prefix.WriteByte(groupKeySep)
@@ -158,10 +205,15 @@ func (b *textBuilder) appendAttr(a slog.Attr) {
 	}
 
 	if a.Value.Kind() != slog.KindGroup {
+		key := string(*b.prefix) + a.Key
 		b.buf.WriteByte(textAttrSep)
-		b.h.WriteColorful(ThemeKey, b.buf, b.quote(string(*b.prefix)+a.Key))
+		b.h.WriteColorful(ThemeKey, b.buf, b.quote(key))
 		b.buf.WriteByte(textComponentSep)
-		b.appendValue(a.Value)
+		theme := b.h.matchRule(key, a.Value)
+		if theme == nil {
+			theme = b.h.keyTheme(b.h.themes[valueSchema(a.Value)], a.Key, key, a.Value)
+		}
+		b.appendValue(a.Value, theme)
 		return
 	}
 
@@ -178,7 +230,10 @@ func (b *textBuilder) appendAttr(a slog.Attr) {
 	}
 }
 
-func (b *textBuilder) appendValue(v slog.Value) {
+// appendValue writes v colored with theme, which is either
+// matched by the style rules or the theme of its kind.
+func (b *textBuilder) appendValue(v slog.Value, theme *Theme) {
+	colored := b.h.beginTheme(theme, b.buf)
 	switch v.Kind() {
 	case slog.KindString:
 		b.buf.WriteString(b.quote(v.String()))
@@ -197,6 +252,9 @@ func (b *textBuilder) appendValue(v slog.Value) {
 	default:
 		b.buf.WriteString(b.quote(v.String()))
 	}
+	if colored {
+		b.h.endColorful(b.buf)
+	}
 }
 
 func (b *textBuilder) output() *Buffer {
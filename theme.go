@@ -3,9 +3,11 @@ package shandler
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/lucasb-eyer/go-colorful"
 	"github.com/muesli/termenv"
+	"log/slog"
 )
 
 // Sequence definitions.
@@ -43,13 +45,117 @@ const (
 	ThemeCaller
 	ThemeKey
 	ThemeBracket // only json handler
+
+	// the themes of the attr values by kind
+	ThemeString
+	ThemeNumber
+	ThemeBool
+	ThemeNull
+	ThemeTimeValue
+	ThemeDuration
+	ThemeErrorValue
+	ThemeAny
+)
+
+// valueSchema returns the theme schema of the attr value v by its kind.
+func valueSchema(v slog.Value) ThemeSchema {
+	switch v.Kind() {
+	case slog.KindString:
+		return ThemeString
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+		return ThemeNumber
+	case slog.KindBool:
+		return ThemeBool
+	case slog.KindTime:
+		return ThemeTimeValue
+	case slog.KindDuration:
+		return ThemeDuration
+	}
+	switch v.Any().(type) {
+	case nil:
+		return ThemeNull
+	case error:
+		return ThemeErrorValue
+	}
+	return ThemeAny
+}
+
+// defaultDark reports whether the terminal of stdout has a dark background,
+// it's only queried once Theme.Format is called.
+var defaultDark = sync.OnceValue(termenv.HasDarkBackground)
+
+// BackgroundMode is the background color of the output, the colors
+// of themes are picked from the light or dark variant by it.
+type BackgroundMode int
+
+const (
+	// BackgroundAuto queries the terminal of the output for its background.
+	BackgroundAuto BackgroundMode = iota
+	// BackgroundLight picks the light variant of the colors.
+	BackgroundLight
+	// BackgroundDark picks the dark variant of the colors.
+	BackgroundDark
+)
+
+// ColorProfile is the set of colors supported by the output,
+// the colors of themes are converted to the nearest ones of it.
+type ColorProfile int
+
+const (
+	// ProfileAuto detects the profile of the output by the environment.
+	ProfileAuto ColorProfile = iota
+	// ProfileTrueColor supports 24-bit colors.
+	ProfileTrueColor
+	// ProfileANSI256 supports the 256-color palette.
+	ProfileANSI256
+	// ProfileANSI supports the 16 basic colors.
+	ProfileANSI
+	// ProfileAscii supports no colors, only the attributes like bold are rendered.
+	ProfileAscii
 )
 
-var hasDarkBackground = termenv.HasDarkBackground()
+func (p ColorProfile) termenv() termenv.Profile {
+	switch p {
+	case ProfileANSI256:
+		return termenv.ANSI256
+	case ProfileANSI:
+		return termenv.ANSI
+	case ProfileAscii:
+		return termenv.Ascii
+	default:
+		return termenv.TrueColor
+	}
+}
+
+func profileOf(p termenv.Profile) ColorProfile {
+	switch p {
+	case termenv.ANSI256:
+		return ProfileANSI256
+	case termenv.ANSI:
+		return ProfileANSI
+	case termenv.Ascii:
+		return ProfileAscii
+	default:
+		return ProfileTrueColor
+	}
+}
+
+// colorPair is a color with the variants for light and dark background.
+type colorPair struct {
+	light, dark colorful.Color
+}
 
 type Theme struct {
 	sequences []string
+	fg, bg    *colorPair
 	formatted []byte
+
+	// variants are formatted for the light and dark background by the handlers,
+	// the one of the output is picked when it's rendered, refer to sequence
+	variants *[2][]byte
+
+	// profile is what formatted and variants are made for
+	profile ColorProfile
 }
 
 func NewTheme() *Theme {
@@ -58,21 +164,13 @@ func NewTheme() *Theme {
 
 // Foreground sets a foreground color.
 func (t *Theme) Foreground(light, dark colorful.Color) *Theme {
-	if hasDarkBackground {
-		t.sequences = append(t.sequences, t.getSequence(dark, false))
-	} else {
-		t.sequences = append(t.sequences, t.getSequence(light, false))
-	}
+	t.fg = &colorPair{light: light, dark: dark}
 	return t
 }
 
 // Background sets a background color.
 func (t *Theme) Background(light, dark colorful.Color) *Theme {
-	if hasDarkBackground {
-		t.sequences = append(t.sequences, t.getSequence(dark, true))
-	} else {
-		t.sequences = append(t.sequences, t.getSequence(light, true))
-	}
+	t.bg = &colorPair{light: light, dark: dark}
 	return t
 }
 
@@ -148,20 +246,82 @@ func (t *Theme) CrossOut(crossOut ...bool) *Theme {
 	return t
 }
 
-func (t *Theme) getSequence(f colorful.Color, bg bool) string {
+func (t *Theme) getSequence(f colorful.Color, bg bool, profile ColorProfile) string {
 	prefix := Foreground
 	if bg {
 		prefix = Background
 	}
+	if profile != ProfileTrueColor {
+		c := profile.termenv().Convert(termenv.RGBColor(f.Hex()))
+		return c.Sequence(bg)
+	}
 	r, g, b := f.RGB255()
 	return fmt.Sprintf("%s;2;%d;%d;%d", prefix, r, g, b)
 }
 
+// Format formats the theme with 24-bit colors for the background of stdout,
+// it's used to Render the theme directly. The handlers don't need it, they
+// format the themes by the color profile and background of their output.
 func (t *Theme) Format() *Theme {
-	t.formatted = []byte(fmt.Sprintf("%s%sm", CSI, strings.Join(t.sequences, separator)))
+	t.format(ProfileTrueColor, defaultDark())
 	return t
 }
 
+func (t *Theme) format(profile ColorProfile, dark bool) {
+	sequences := make([]string, 0, len(t.sequences)+2)
+	for i, pair := range []*colorPair{t.fg, t.bg} {
+		if pair == nil {
+			continue
+		}
+		color := pair.light
+		if dark {
+			color = pair.dark
+		}
+		if seq := t.getSequence(color, i == 1, profile); seq != "" {
+			sequences = append(sequences, seq)
+		}
+	}
+	sequences = append(sequences, t.sequences...)
+	t.profile = profile
+	if len(sequences) == 0 {
+		t.formatted = nil
+		return
+	}
+	t.formatted = []byte(fmt.Sprintf("%s%sm", CSI, strings.Join(sequences, separator)))
+}
+
+// profiled returns the theme formatted for profile with the variants of both
+// backgrounds, t is returned if it's already the one, otherwise t is copied
+// and left unchanged.
+func (t *Theme) profiled(profile ColorProfile) *Theme {
+	if t.variants != nil && t.profile == profile {
+		return t
+	}
+	t2 := *t
+	t2.formatVariants(profile)
+	return &t2
+}
+
+// formatVariants formats the variants of the light and dark background for profile.
+func (t *Theme) formatVariants(profile ColorProfile) {
+	t.format(profile, true)
+	dark := t.formatted
+	t.format(profile, false)
+	t.variants = &[2][]byte{t.formatted, dark}
+}
+
+// sequence returns the sequence of the theme for the background,
+// it's the one of Format if the variants aren't formatted.
+func (t *Theme) sequence(dark bool) []byte {
+	switch {
+	case t.variants == nil:
+		return t.formatted
+	case dark:
+		return t.variants[1]
+	}
+	return t.variants[0]
+}
+
 func (t *Theme) Render(s string) string {
 	return string(t.formatted) + s + string(CSI) + string(ResetSeq) + "m"
 }
@@ -174,14 +334,41 @@ func (t *Theme) WriteRendered(buf *Buffer, s string) {
 	buf.WriteByte('m')
 }
 
+// beginColorful writes the schema's sequence if the output can be colored,
+// it reports whether endColorful must be called after the content is written.
+func (h *baseHandler) beginColorful(schema ThemeSchema, buf *Buffer) bool {
+	return h.beginTheme(h.themes[schema], buf)
+}
+
+// beginTheme is like beginColorful with the theme given directly.
+func (h *baseHandler) beginTheme(theme *Theme, buf *Buffer) bool {
+	if !h.colored || theme == nil {
+		return false
+	}
+	seq := theme.sequence(h.isDark())
+	if len(seq) == 0 {
+		return false
+	}
+	buf.Write(seq)
+	return true
+}
+
+func (h *baseHandler) endColorful(buf *Buffer) {
+	buf.Write(CSI)
+	buf.WriteByte(ResetSeq)
+	buf.WriteByte('m')
+}
+
 func (h *baseHandler) WriteColorful(schema ThemeSchema, buf *Buffer, s string) {
-	if !h.tty {
+	h.writeTheme(h.themes[schema], buf, s)
+}
+
+// writeTheme writes s rendered with theme if the output can be colored.
+func (h *baseHandler) writeTheme(theme *Theme, buf *Buffer, s string) {
+	if !h.beginTheme(theme, buf) {
 		buf.WriteString(s)
 		return
 	}
-	if theme, ok := h.themes[schema]; ok {
-		theme.WriteRendered(buf, s)
-		return
-	}
 	buf.WriteString(s)
+	h.endColorful(buf)
 }
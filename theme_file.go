@@ -0,0 +1,232 @@
+package shandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lucasb-eyer/go-colorful"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the theme files, refer to ParseThemes.
+const (
+	ThemeFormatJSON = "json"
+	ThemeFormatTOML = "toml"
+	ThemeFormatYAML = "yaml"
+)
+
+// ThemeStyle is the style of a schema in a theme file.
+type ThemeStyle struct {
+	Foreground *ThemeColor `json:"foreground,omitempty"`
+	Background *ThemeColor `json:"background,omitempty"`
+	Bold       bool        `json:"bold,omitempty"`
+	Faint      bool        `json:"faint,omitempty"`
+	Italic     bool        `json:"italic,omitempty"`
+	Underline  bool        `json:"underline,omitempty"`
+	Overline   bool        `json:"overline,omitempty"`
+	Blink      bool        `json:"blink,omitempty"`
+	Reverse    bool        `json:"reverse,omitempty"`
+	CrossOut   bool        `json:"crossout,omitempty"`
+}
+
+// ThemeColor is a color with the variants for light and dark background,
+// it's either a hex string used by both or an object as {"light":..., "dark":...}.
+type ThemeColor struct {
+	Light colorful.Color
+	Dark  colorful.Color
+}
+
+func (c *ThemeColor) UnmarshalJSON(data []byte) error {
+	var hex string
+	if err := json.Unmarshal(data, &hex); err == nil {
+		color, err := parseHex(hex)
+		c.Light, c.Dark = color, color
+		return err
+	}
+
+	var pair struct {
+		Light *string `json:"light"`
+		Dark  *string `json:"dark"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&pair); err != nil {
+		return fmt.Errorf("color must be a hex string or {light, dark}: %w", err)
+	}
+	if pair.Light == nil && pair.Dark == nil {
+		return errors.New("color must have light or dark")
+	}
+	if pair.Light == nil {
+		pair.Light = pair.Dark
+	} else if pair.Dark == nil {
+		pair.Dark = pair.Light
+	}
+
+	var err error
+	if c.Light, err = parseHex(*pair.Light); err != nil {
+		return err
+	}
+	c.Dark, err = parseHex(*pair.Dark)
+	return err
+}
+
+// parseHex parses the hex color strictly, colorful.Hex accepts some invalid digits.
+func parseHex(hex string) (colorful.Color, error) {
+	valid := len(hex) == 4 || len(hex) == 7
+	for i := 1; valid && i < len(hex); i++ {
+		_, err := strconv.ParseUint(hex[i:i+1], 16, 8)
+		valid = err == nil
+	}
+	c, err := colorful.Hex(hex)
+	if err != nil || !valid {
+		return c, fmt.Errorf("invalid color %q, expected #rrggbb or #rgb", hex)
+	}
+	return c, nil
+}
+
+// Theme creates the Theme of the style.
+func (s ThemeStyle) Theme() *Theme {
+	t := NewTheme()
+	if s.Foreground != nil {
+		t.Foreground(s.Foreground.Light, s.Foreground.Dark)
+	}
+	if s.Background != nil {
+		t.Background(s.Background.Light, s.Background.Dark)
+	}
+	t.Bold(s.Bold).Faint(s.Faint).Italic(s.Italic).Underline(s.Underline)
+	t.Overline(s.Overline).Blink(s.Blink).Reverse(s.Reverse).CrossOut(s.CrossOut)
+	return t
+}
+
+// LoadThemes reads the theme file at path, the format is picked by
+// the extension: .json, .toml, .yaml or .yml. Refer to ParseThemes.
+func LoadThemes(path string) (Themes, error) {
+	var format string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		format = ThemeFormatJSON
+	case ".toml":
+		format = ThemeFormatTOML
+	case ".yaml", ".yml":
+		format = ThemeFormatYAML
+	default:
+		return nil, fmt.Errorf("shandler: theme file %s: unsupported extension %q", path, ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("shandler: theme file: %w", err)
+	}
+	themes, err := ParseThemes(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s", err, path)
+	}
+	return themes, nil
+}
+
+// ParseThemes parses the themes mapping the schema names to the styles, the
+// result goes to WithThemes or CopyWithThemes. The names are matched case
+// insensitively and the prefix "Theme" can be omitted, e.g. "ThemeError" or "error".
+//
+//	{
+//	  "error": {"foreground": {"light": "#ff000a", "dark": "#ff4f86"}, "bold": true},
+//	  "key": {"foreground": "#7f7f7f", "italic": true},
+//	  "caller": "italic gray"
+//	}
+//
+// A style is either an object like above or a spec string parsed by ParseTheme.
+// The TOML and YAML documents have the same structure, the hex colors
+// must be quoted in YAML, otherwise they're comments.
+func ParseThemes(data []byte, format string) (Themes, error) {
+	var doc map[string]any
+	var err error
+	switch format {
+	case ThemeFormatJSON:
+		err = json.Unmarshal(data, &doc)
+	case ThemeFormatTOML:
+		err = toml.Unmarshal(data, &doc)
+	case ThemeFormatYAML:
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("shandler: unsupported theme format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shandler: invalid %s theme: %w", format, err)
+	}
+
+	themes := make(Themes, len(doc))
+	for name, value := range doc {
+		schema, err := themeSchemaOf(name)
+		if err != nil {
+			return nil, err
+		}
+		if isEmptyStyle(value) {
+			return nil, fmt.Errorf("shandler: theme %s: empty style of %s", name, schema)
+		}
+		if spec, ok := value.(string); ok {
+			if themes[schema], err = ParseTheme(spec); err != nil {
+				return nil, fmt.Errorf("%w for %s", err, schema)
+			}
+			continue
+		}
+		style, err := decodeThemeStyle(value)
+		if err != nil {
+			return nil, fmt.Errorf("shandler: theme %s: %w", name, err)
+		}
+		themes[schema] = style.Theme()
+	}
+	return themes, nil
+}
+
+func themeSchemaOf(name string) (ThemeSchema, error) {
+	if schema, err := ThemeSchemaString(name); err == nil {
+		return schema, nil
+	}
+	if schema, err := ThemeSchemaString("Theme" + name); err == nil {
+		return schema, nil
+	}
+	return 0, fmt.Errorf("shandler: unknown theme schema %q, expected one of %s",
+		name, strings.Join(ThemeSchemaStrings(), ", "))
+}
+
+// isEmptyStyle reports whether the style is missing, e.g. a bare key of YAML.
+func isEmptyStyle(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// decodeThemeStyle decodes the style from the parsed document,
+// it goes through JSON so that every format is validated the same way.
+func decodeThemeStyle(value any) (ThemeStyle, error) {
+	var style ThemeStyle
+	if fields, ok := value.(map[string]any); ok {
+		for field, v := range fields {
+			// e.g. an unquoted hex color of YAML is a comment
+			if v == nil {
+				return style, fmt.Errorf("missing value of %s", field)
+			}
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return style, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&style)
+	return style, err
+}
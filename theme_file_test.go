@@ -0,0 +1,146 @@
+package shandler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseThemes(t *testing.T) {
+	docs := map[string]string{
+		ThemeFormatJSON: `{
+  "ThemeError": {"foreground": {"light": "#ff000a", "dark": "#ff4f86"}, "bold": true},
+  "key": {"foreground": "#7f7f7f", "background": {"dark": "#202020"}, "italic": true, "underline": true}
+}`,
+		ThemeFormatTOML: `# error is red
+[ThemeError]
+foreground = { light = "#ff000a", dark = '#ff4f86' }
+bold = true
+
+[key]
+foreground = "#7f7f7f" # gray
+background.dark = "#202020"
+italic = true
+underline = true
+`,
+		ThemeFormatYAML: `---
+ThemeError:
+  foreground:
+    light: "#ff000a"
+    dark: '#ff4f86'
+  bold: true
+# gray keys
+key:
+  foreground: "#7f7f7f"
+  background: {dark: "#202020"}
+  italic: true
+  underline: true
+`,
+	}
+
+	want := map[ThemeSchema]string{
+		ThemeError: "\x1b[38;2;255;79;134;1m",
+		ThemeKey:   "\x1b[38;2;127;127;127;48;2;32;32;32;3;4m",
+	}
+	for format, doc := range docs {
+		themes, err := ParseThemes([]byte(doc), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(themes) != len(want) {
+			t.Fatalf("%s: got %d themes, want %d", format, len(themes), len(want))
+		}
+		for schema, seq := range want {
+			theme := themes[schema].profiled(ProfileTrueColor)
+			if got := string(theme.sequence(true)); got != seq {
+				t.Errorf("%s: %s got %q, want %q", format, schema, got, seq)
+			}
+		}
+	}
+
+	// the syntax beyond the basic documents
+	full := map[string]string{
+		ThemeFormatTOML: `"ThemeError" = { foreground = """#ff4f86""", bold = true }
+[ 'key' ]
+foreground = "#7f7f7f"
+background = { dark = "#202020" }
+italic = true
+underline = true
+`,
+		ThemeFormatYAML: `ThemeError: >-
+  bold
+  #ff4f86
+key: &gray
+  foreground: "#7f7f7f"
+  background: {dark: "#202020"}
+  italic: true
+  underline: true
+caller:
+  <<: *gray
+  bold: true
+`,
+	}
+	for format, doc := range full {
+		themes, err := ParseThemes([]byte(doc), format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		for schema, seq := range want {
+			if got := string(themes[schema].profiled(ProfileTrueColor).sequence(true)); got != seq {
+				t.Errorf("%s: %s got %q, want %q", format, schema, got, seq)
+			}
+		}
+	}
+
+	themes, err := ParseThemes([]byte("caller = \"italic gray\""), ThemeFormatTOML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(themes[ThemeCaller].profiled(ProfileANSI).sequence(true)), "\x1b[90;3m"; got != want {
+		t.Errorf("spec: got %q, want %q", got, want)
+	}
+
+	errs := []struct {
+		format, doc, err string
+	}{
+		{ThemeFormatJSON, `{"ThemeColor": {}}`, `unknown theme schema "ThemeColor"`},
+		{ThemeFormatJSON, `{"error": {"foreground": "red"}}`, `invalid color "red"`},
+		{ThemeFormatJSON, `{"error": {"foreground": {"light": "#zzzzzz"}}}`, `invalid color "#zzzzzz"`},
+		{ThemeFormatJSON, `{"error": {"bolt": true}}`, `unknown field "bolt"`},
+		{ThemeFormatJSON, `{"error": "bold purple"}`, `unknown word "purple" for ThemeError`},
+		{ThemeFormatJSON, `{"error": null}`, "theme error: empty style of ThemeError"},
+		{ThemeFormatJSON, `{"error": {}}`, "theme error: empty style of ThemeError"},
+		{ThemeFormatTOML, "[error]\nbold = yes", "invalid toml theme: toml: line 2"},
+		{ThemeFormatTOML, "[error\nbold = true", "invalid toml theme: toml: line 2"},
+		{ThemeFormatTOML, "caller = \"\"", "theme caller: empty style of ThemeCaller"},
+		{ThemeFormatYAML, "error:\n  bold: true\n    italic: true", "invalid yaml theme: yaml: line 3"},
+		{ThemeFormatYAML, "error:\n  foreground: #ff0000", `theme error: missing value of foreground`},
+		{ThemeFormatYAML, "info:\nerror:\n  bold: true", "theme info: empty style of ThemeInfo"},
+		{"ini", "", `unsupported theme format "ini"`},
+	}
+	for _, tt := range errs {
+		_, err := ParseThemes([]byte(tt.doc), tt.format)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s %q: got error %v, want %q", tt.format, tt.doc, err, tt.err)
+		}
+	}
+}
+
+func TestLoadThemes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.yml")
+	if err := os.WriteFile(path, []byte("info:\n  foreground: \"#00ffd5\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	themes, err := LoadThemes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if themes[ThemeInfo] == nil {
+		t.Errorf("got %v, want ThemeInfo", themes)
+	}
+
+	if _, err := LoadThemes("theme.ini"); err == nil {
+		t.Error("want error of the unsupported extension")
+	}
+}
@@ -0,0 +1,202 @@
+package shandler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/muesli/termenv"
+)
+
+const (
+	// ThemeEnvPrefix is the prefix of the environment variables read by ThemesFromEnv
+	// by default, followed by the schema name without "Theme", e.g. SHANDLER_THEME_ERROR.
+	ThemeEnvPrefix = "SHANDLER_THEME_"
+
+	specOn   = "on"
+	specPair = '|'
+)
+
+// specNames the ANSI names of the 16 basic colors, by index.
+var specNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+}
+
+// specAttrs the attributes of the theme spec, by name.
+var specAttrs = map[string]func(*Theme) *Theme{
+	"bold":      func(t *Theme) *Theme { return t.Bold() },
+	"faint":     func(t *Theme) *Theme { return t.Faint() },
+	"dim":       func(t *Theme) *Theme { return t.Faint() },
+	"italic":    func(t *Theme) *Theme { return t.Italic() },
+	"underline": func(t *Theme) *Theme { return t.Underline() },
+	"ul":        func(t *Theme) *Theme { return t.Underline() },
+	"overline":  func(t *Theme) *Theme { return t.Overline() },
+	"blink":     func(t *Theme) *Theme { return t.Blink() },
+	"reverse":   func(t *Theme) *Theme { return t.Reverse() },
+	"crossout":  func(t *Theme) *Theme { return t.CrossOut() },
+	"strike":    func(t *Theme) *Theme { return t.CrossOut() },
+}
+
+// ParseTheme parses the theme from a spec like git's color config, the words
+// separated by spaces are attributes and colors. The first color is the
+// foreground and the second is the background, which can also be led by "on".
+// "normal" leaves the color unset.
+//
+//	bold underline #ff0000 on #202020
+//	italic #0000ff|#82aaff
+//	normal on 236
+//
+// The attributes are bold, faint (dim), italic, underline (ul), overline,
+// blink, reverse and crossout (strike). A color is a hex like #rrggbb or #rgb,
+// an index of the 256-color palette, or an ANSI name such as red and
+// bright-red, gray is bright-black. The variants for light and dark
+// background are separated by '|', one color is used by both.
+func ParseTheme(spec string) (*Theme, error) {
+	t := NewTheme()
+	var colors []*colorPair
+	on := false
+	for _, word := range strings.Fields(strings.ToLower(spec)) {
+		if attr, ok := specAttrs[word]; ok {
+			attr(t)
+			continue
+		}
+
+		if word == specOn {
+			if on || len(colors) > 1 {
+				return nil, fmt.Errorf("shandler: theme spec %q: unexpected %s", spec, specOn)
+			}
+			on = true
+			if len(colors) == 0 {
+				colors = append(colors, nil)
+			}
+			continue
+		}
+
+		pair, err := parseColorPair(word)
+		if err != nil {
+			return nil, fmt.Errorf("shandler: theme spec %q: %w", spec, err)
+		}
+		if len(colors) == 2 {
+			return nil, fmt.Errorf("shandler: theme spec %q: too many colors", spec)
+		}
+		colors = append(colors, pair)
+	}
+
+	if on && len(colors) < 2 {
+		return nil, fmt.Errorf("shandler: theme spec %q: missing color after %s", spec, specOn)
+	}
+	if len(colors) > 0 {
+		t.fg = colors[0]
+	}
+	if len(colors) > 1 {
+		t.bg = colors[1]
+	}
+	return t, nil
+}
+
+// parseColorPair parses the color of the theme spec, it's nil if "normal".
+func parseColorPair(word string) (*colorPair, error) {
+	if word == "normal" {
+		return nil, nil
+	}
+	light, dark, found := strings.Cut(word, string(specPair))
+	if !found {
+		dark = light
+	}
+	var pair colorPair
+	var err error
+	if pair.light, err = parseColor(light); err != nil {
+		return nil, err
+	}
+	if pair.dark, err = parseColor(dark); err != nil {
+		return nil, err
+	}
+	return &pair, nil
+}
+
+// parseColor parses a single color of the theme spec.
+func parseColor(s string) (colorful.Color, error) {
+	if strings.HasPrefix(s, "#") {
+		return parseHex(s)
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n > 255 {
+			return colorful.Color{}, fmt.Errorf("color index %d out of 0-255", n)
+		}
+		return termenv.ConvertToRGB(termenv.ANSI256Color(n)), nil
+	}
+
+	name, bright := s, false
+	for _, p := range []string{"bright-", "bright_", "bright"} {
+		if strings.HasPrefix(s, p) {
+			name, bright = s[len(p):], true
+			break
+		}
+	}
+	if name == "gray" || name == "grey" {
+		name, bright = "black", !bright
+	}
+	for i, n := range specNames {
+		if n == name {
+			if bright {
+				i += len(specNames)
+			}
+			return termenv.ConvertToRGB(termenv.ANSIColor(i)), nil
+		}
+	}
+	return colorful.Color{}, fmt.Errorf("unknown word %q", s)
+}
+
+// ThemesFromEnv parses the themes from the specs of the environment variables
+// named by prefix and the schema, the prefix "Theme" and the underscores of the
+// schema can be omitted, e.g. TIME_VALUE for ThemeTimeValue, see ParseTheme.
+// ThemeEnvPrefix is used if prefix is empty. The themes of the valid specs
+// are returned along with the errors of the others.
+//
+//	SHANDLER_THEME_ERROR="bold #ff0000"
+//	SHANDLER_THEME_KEY="italic gray"
+func ThemesFromEnv(prefix string) (Themes, error) {
+	if prefix == "" {
+		prefix = ThemeEnvPrefix
+	}
+	env := os.Environ()
+	slices.Sort(env)
+
+	themes := make(Themes)
+	var errs []error
+	for _, kv := range env {
+		key, spec, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		schema, err := themeSchemaOf(strings.ReplaceAll(name, "_", ""))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w in %s", err, key))
+			continue
+		}
+		theme, err := ParseTheme(spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w in %s", err, key))
+			continue
+		}
+		themes[schema] = theme
+	}
+	return themes, errors.Join(errs...)
+}
+
+// WithThemeEnv sets the themes from the environment variables, see ThemesFromEnv.
+// The invalid variables are skipped silently, ThemesFromEnv returns their errors,
+// e.g. to report them and pass its themes to CopyWithThemes.
+func WithThemeEnv(prefix string) Option {
+	return func(cfg *baseHandler) {
+		themes, _ := ThemesFromEnv(prefix)
+		for schema, theme := range themes {
+			cfg.themes[schema] = theme
+		}
+	}
+}
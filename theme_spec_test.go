@@ -0,0 +1,89 @@
+package shandler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseTheme(t *testing.T) {
+	tests := []struct {
+		spec  string
+		light string
+		dark  string
+	}{
+		{"bold underline #ff0000 on #202020", "\x1b[38;2;255;0;0;48;2;32;32;32;1;4m", "\x1b[38;2;255;0;0;48;2;32;32;32;1;4m"},
+		{"italic #00f|#82aaff", "\x1b[38;2;0;0;255;3m", "\x1b[38;2;130;170;255;3m"},
+		{"normal on 236", "\x1b[48;2;48;48;48m", "\x1b[48;2;48;48;48m"},
+		{"Red Bright-Blue", "\x1b[38;2;128;0;0;48;2;0;0;255m", "\x1b[38;2;128;0;0;48;2;0;0;255m"},
+		{"dim gray|white", "\x1b[38;2;128;128;128;2m", "\x1b[38;2;192;192;192;2m"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		theme, err := ParseTheme(tt.spec)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.spec, err)
+		}
+		if got := string(theme.profiled(ProfileTrueColor).sequence(false)); got != tt.light {
+			t.Errorf("%q light: got %q, want %q", tt.spec, got, tt.light)
+		}
+		if got := string(theme.profiled(ProfileTrueColor).sequence(true)); got != tt.dark {
+			t.Errorf("%q dark: got %q, want %q", tt.spec, got, tt.dark)
+		}
+	}
+
+	// the ANSI names are rendered as the basic colors by the ANSI profile
+	theme, _ := ParseTheme("red on bright-blue")
+	if got, want := string(theme.profiled(ProfileANSI).sequence(true)), "\x1b[31;104m"; got != want {
+		t.Errorf("ANSI: got %q, want %q", got, want)
+	}
+
+	errs := map[string]string{
+		"bold purple":       `unknown word "purple"`,
+		"#ff0000 on":        "missing color after on",
+		"red on blue on":    "unexpected on",
+		"red blue green":    "too many colors",
+		"256":               "color index 256 out of 0-255",
+		"#ff00zz":           `invalid color "#ff00zz"`,
+		"bold #fff|#00000g": `invalid color "#00000g"`,
+	}
+	for spec, want := range errs {
+		if _, err := ParseTheme(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got error %v, want %q", spec, err, want)
+		}
+	}
+}
+
+func TestThemeEnv(t *testing.T) {
+	t.Setenv("SHANDLER_THEME_ERROR", "bold #ff0000")
+	t.Setenv("SHANDLER_THEME_KEY", "bold purple")
+	var buf bytes.Buffer
+	h := createHandler(false, WithWriter(&buf), WithColorMode(ColorAlways),
+		WithColorProfile(ProfileTrueColor), WithBackground(BackgroundDark), WithThemeEnv(""))
+	if got, want := string(h.themes[ThemeError].sequence(h.isDark())), "\x1b[38;2;255;0;0;1m"; got != want {
+		t.Errorf("error: got %q, want %q", got, want)
+	}
+	// the invalid spec is skipped, the preset is used
+	if got, want := string(h.themes[ThemeKey].sequence(h.isDark())), "\x1b[38;2;127;127;127;1m"; got != want {
+		t.Errorf("key: got %q, want %q", got, want)
+	}
+}
+
+func TestThemesFromEnv(t *testing.T) {
+	t.Setenv("APP_THEME_ERROR", "bold #ff0000")
+	t.Setenv("APP_THEME_ThemeKey", "bold purple")
+	t.Setenv("APP_THEME_EROR", "bold")
+	t.Setenv("APP_THEME_TIME_VALUE", "italic")
+	themes, err := ThemesFromEnv("APP_THEME_")
+	if themes[ThemeError] == nil || themes[ThemeTimeValue] == nil || len(themes) != 2 {
+		t.Errorf("got %v, want the themes of error and time value", themes)
+	}
+	for _, want := range []string{
+		`unknown word "purple" in APP_THEME_ThemeKey`,
+		`unknown theme schema "EROR"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want %q", err, want)
+		}
+	}
+}
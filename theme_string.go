@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _ThemeSchemaName = "ThemeTimeThemeDebugThemeInfoThemeWarnThemeErrorThemePrefixThemeCallerThemeKeyThemeBracket"
+const _ThemeSchemaName = "ThemeTimeThemeDebugThemeInfoThemeWarnThemeErrorThemePrefixThemeCallerThemeKeyThemeBracketThemeStringThemeNumberThemeBoolThemeNullThemeTimeValueThemeDurationThemeErrorValueThemeAny"
 
-var _ThemeSchemaIndex = [...]uint8{0, 9, 19, 28, 37, 47, 58, 69, 77, 89}
+var _ThemeSchemaIndex = [...]uint8{0, 9, 19, 28, 37, 47, 58, 69, 77, 89, 100, 111, 120, 129, 143, 156, 171, 179}
 
-const _ThemeSchemaLowerName = "themetimethemedebugthemeinfothemewarnthemeerrorthemeprefixthemecallerthemekeythemebracket"
+const _ThemeSchemaLowerName = "themetimethemedebugthemeinfothemewarnthemeerrorthemeprefixthemecallerthemekeythemebracketthemestringthemenumberthemeboolthemenullthemetimevaluethemedurationthemeerrorvaluethemeany"
 
 func (i ThemeSchema) String() string {
 	i -= 1
@@ -34,29 +34,53 @@ func _ThemeSchemaNoOp() {
 	_ = x[ThemeCaller-(7)]
 	_ = x[ThemeKey-(8)]
 	_ = x[ThemeBracket-(9)]
+	_ = x[ThemeString-(10)]
+	_ = x[ThemeNumber-(11)]
+	_ = x[ThemeBool-(12)]
+	_ = x[ThemeNull-(13)]
+	_ = x[ThemeTimeValue-(14)]
+	_ = x[ThemeDuration-(15)]
+	_ = x[ThemeErrorValue-(16)]
+	_ = x[ThemeAny-(17)]
 }
 
-var _ThemeSchemaValues = []ThemeSchema{ThemeTime, ThemeDebug, ThemeInfo, ThemeWarn, ThemeError, ThemePrefix, ThemeCaller, ThemeKey, ThemeBracket}
+var _ThemeSchemaValues = []ThemeSchema{ThemeTime, ThemeDebug, ThemeInfo, ThemeWarn, ThemeError, ThemePrefix, ThemeCaller, ThemeKey, ThemeBracket, ThemeString, ThemeNumber, ThemeBool, ThemeNull, ThemeTimeValue, ThemeDuration, ThemeErrorValue, ThemeAny}
 
 var _ThemeSchemaNameToValueMap = map[string]ThemeSchema{
-	_ThemeSchemaName[0:9]:        ThemeTime,
-	_ThemeSchemaLowerName[0:9]:   ThemeTime,
-	_ThemeSchemaName[9:19]:       ThemeDebug,
-	_ThemeSchemaLowerName[9:19]:  ThemeDebug,
-	_ThemeSchemaName[19:28]:      ThemeInfo,
-	_ThemeSchemaLowerName[19:28]: ThemeInfo,
-	_ThemeSchemaName[28:37]:      ThemeWarn,
-	_ThemeSchemaLowerName[28:37]: ThemeWarn,
-	_ThemeSchemaName[37:47]:      ThemeError,
-	_ThemeSchemaLowerName[37:47]: ThemeError,
-	_ThemeSchemaName[47:58]:      ThemePrefix,
-	_ThemeSchemaLowerName[47:58]: ThemePrefix,
-	_ThemeSchemaName[58:69]:      ThemeCaller,
-	_ThemeSchemaLowerName[58:69]: ThemeCaller,
-	_ThemeSchemaName[69:77]:      ThemeKey,
-	_ThemeSchemaLowerName[69:77]: ThemeKey,
-	_ThemeSchemaName[77:89]:      ThemeBracket,
-	_ThemeSchemaLowerName[77:89]: ThemeBracket,
+	_ThemeSchemaName[0:9]:          ThemeTime,
+	_ThemeSchemaLowerName[0:9]:     ThemeTime,
+	_ThemeSchemaName[9:19]:         ThemeDebug,
+	_ThemeSchemaLowerName[9:19]:    ThemeDebug,
+	_ThemeSchemaName[19:28]:        ThemeInfo,
+	_ThemeSchemaLowerName[19:28]:   ThemeInfo,
+	_ThemeSchemaName[28:37]:        ThemeWarn,
+	_ThemeSchemaLowerName[28:37]:   ThemeWarn,
+	_ThemeSchemaName[37:47]:        ThemeError,
+	_ThemeSchemaLowerName[37:47]:   ThemeError,
+	_ThemeSchemaName[47:58]:        ThemePrefix,
+	_ThemeSchemaLowerName[47:58]:   ThemePrefix,
+	_ThemeSchemaName[58:69]:        ThemeCaller,
+	_ThemeSchemaLowerName[58:69]:   ThemeCaller,
+	_ThemeSchemaName[69:77]:        ThemeKey,
+	_ThemeSchemaLowerName[69:77]:   ThemeKey,
+	_ThemeSchemaName[77:89]:        ThemeBracket,
+	_ThemeSchemaLowerName[77:89]:   ThemeBracket,
+	_ThemeSchemaName[89:100]:       ThemeString,
+	_ThemeSchemaLowerName[89:100]:  ThemeString,
+	_ThemeSchemaName[100:111]:      ThemeNumber,
+	_ThemeSchemaLowerName[100:111]: ThemeNumber,
+	_ThemeSchemaName[111:120]:      ThemeBool,
+	_ThemeSchemaLowerName[111:120]: ThemeBool,
+	_ThemeSchemaName[120:129]:      ThemeNull,
+	_ThemeSchemaLowerName[120:129]: ThemeNull,
+	_ThemeSchemaName[129:143]:      ThemeTimeValue,
+	_ThemeSchemaLowerName[129:143]: ThemeTimeValue,
+	_ThemeSchemaName[143:156]:      ThemeDuration,
+	_ThemeSchemaLowerName[143:156]: ThemeDuration,
+	_ThemeSchemaName[156:171]:      ThemeErrorValue,
+	_ThemeSchemaLowerName[156:171]: ThemeErrorValue,
+	_ThemeSchemaName[171:179]:      ThemeAny,
+	_ThemeSchemaLowerName[171:179]: ThemeAny,
 }
 
 var _ThemeSchemaNames = []string{
@@ -69,6 +93,14 @@ var _ThemeSchemaNames = []string{
 	_ThemeSchemaName[58:69],
 	_ThemeSchemaName[69:77],
 	_ThemeSchemaName[77:89],
+	_ThemeSchemaName[89:100],
+	_ThemeSchemaName[100:111],
+	_ThemeSchemaName[111:120],
+	_ThemeSchemaName[120:129],
+	_ThemeSchemaName[129:143],
+	_ThemeSchemaName[143:156],
+	_ThemeSchemaName[156:171],
+	_ThemeSchemaName[171:179],
 }
 
 // ThemeSchemaString retrieves an enum value from the enum constants string name.
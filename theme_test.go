@@ -1,10 +1,16 @@
 package shandler
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/lucasb-eyer/go-colorful"
+	"log/slog"
 )
 
 func TestTheme(t *testing.T) {
@@ -70,3 +76,153 @@ func TestTheme(t *testing.T) {
 		println(message, fmt.Sprintf("%q", message))
 	}
 }
+
+func TestThemeProfile(t *testing.T) {
+	red, _ := colorful.Hex("#ff0000")
+	theme := NewTheme().Foreground(red, red).Background(red, red).Bold().Format()
+
+	tests := []struct {
+		profile ColorProfile
+		want    string
+	}{
+		{ProfileTrueColor, "\x1b[38;2;255;0;0;48;2;255;0;0;1m"},
+		{ProfileANSI256, "\x1b[38;5;196;48;5;196;1m"},
+		{ProfileANSI, "\x1b[91;101;1m"},
+		{ProfileAscii, "\x1b[1m"},
+	}
+	for _, test := range tests {
+		got := string(theme.profiled(test.profile).sequence(true))
+		if got != test.want {
+			t.Errorf("%d: got %q, want %q", test.profile, got, test.want)
+		}
+	}
+	if got := string(theme.formatted); got != tests[0].want {
+		t.Errorf("the theme is changed by profiled: %q", got)
+	}
+}
+
+func TestThemeBackground(t *testing.T) {
+	light, _ := colorful.Hex("#ff0000")
+	dark, _ := colorful.Hex("#0000ff")
+	theme := NewTheme().Foreground(light, dark)
+
+	tests := []struct {
+		background BackgroundMode
+		want       string
+	}{
+		{BackgroundLight, "\x1b[38;2;255;0;0m"},
+		{BackgroundDark, "\x1b[38;2;0;0;255m"},
+	}
+	for _, test := range tests {
+		h := NewTextHandler(
+			WithWriter(io.Discard),
+			WithColorMode(ColorAlways),
+			WithColorProfile(ProfileTrueColor),
+			WithBackground(test.background),
+			WithTheme(ThemeKey, theme),
+		)
+		if got := string(h.themes[ThemeKey].sequence(h.isDark())); got != test.want {
+			t.Errorf("%d: got %q, want %q", test.background, got, test.want)
+		}
+	}
+	if theme.formatted != nil {
+		t.Errorf("the theme is formatted by the handler")
+	}
+
+	// the background is resolved when the record is rendered
+	var buf bytes.Buffer
+	h := NewTextHandler(
+		WithWriter(&buf),
+		WithColorMode(ColorAlways),
+		WithColorProfile(ProfileTrueColor),
+		WithTheme(ThemeKey, theme),
+	)
+	h.queried.once.Do(func() { h.queried.dark = true })
+	slog.New(h.WithPrefix("app")).Info("msg", "k", 1)
+	if want := tests[1].want + "k"; !strings.Contains(buf.String(), want) {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	white, black := colorful.Color{R: 1, G: 1, B: 1}, colorful.Color{}
+	for _, name := range Presets() {
+		for _, schema := range ThemeSchemaValues() {
+			style, ok := presets[name][schema]
+			if !ok {
+				t.Errorf("%s: missing %s", name, schema)
+				continue
+			}
+			light, err1 := colorful.Hex(style.light)
+			dark, err2 := colorful.Hex(style.dark)
+			if err1 != nil || err2 != nil {
+				t.Errorf("%s: invalid color of %s: %v %v", name, schema, err1, err2)
+			}
+			if name != PresetHighContrast {
+				continue
+			}
+			if r := contrastRatio(light, white); r < minHighContrast {
+				t.Errorf("%s: contrast of %s on light is %.2f", name, schema, r)
+			}
+			if r := contrastRatio(dark, black); r < minHighContrast {
+				t.Errorf("%s: contrast of %s on dark is %.2f", name, schema, r)
+			}
+		}
+	}
+
+	key := NewTheme().Underline()
+	h := NewTextHandler(
+		WithWriter(io.Discard),
+		WithColorMode(ColorAlways),
+		WithColorProfile(ProfileTrueColor),
+		WithBackground(BackgroundDark),
+		WithTheme(ThemeKey, key),
+		WithPreset(PresetDracula),
+	)
+	if got, want := string(h.themes[ThemeKey].sequence(h.isDark())), "\x1b[4m"; got != want {
+		t.Errorf("got key %q, want %q", got, want)
+	}
+	if got, want := string(h.themes[ThemeInfo].sequence(h.isDark())), "\x1b[38;2;139;233;253;1m"; got != want {
+		t.Errorf("got info %q, want %q", got, want)
+	}
+}
+
+func TestParsePreset(t *testing.T) {
+	if name, err := ParsePreset(" Dracula "); err != nil || name != PresetDracula {
+		t.Errorf("got %q %v, want %q", name, err, PresetDracula)
+	}
+	if _, err := ParsePreset("drakula"); err == nil || !strings.Contains(err.Error(), `"drakula"`) {
+		t.Errorf("got %v, want the unknown preset error", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("WithPreset doesn't panic of the unknown preset")
+		}
+	}()
+	WithPreset("drakula")
+}
+
+func TestValueThemes(t *testing.T) {
+	var buf bytes.Buffer
+	themes := Themes{}
+	for i, schema := range []ThemeSchema{
+		ThemeString, ThemeNumber, ThemeBool, ThemeNull,
+		ThemeTimeValue, ThemeDuration, ThemeErrorValue, ThemeAny,
+	} {
+		themes[schema] = NewTheme().Underline().Bold(i%2 == 0)
+	}
+	h := NewTextHandler(WithWriter(&buf), WithColorMode(ColorAlways), WithColorProfile(ProfileAscii))
+	slog.New(h.WithThemes(themes)).Info("", "s", "v", "n", 1, "b", true, "nil", nil,
+		"t", time.Time{}, "d", time.Second, "err", io.EOF, "any", []int{1})
+
+	values := regexp.MustCompile(`=(\x1b\[[0-9;]*m)`).FindAllStringSubmatch(buf.String(), -1)
+	want := []string{"4;1", "4", "4;1", "4", "4;1", "4", "4;1", "4"}
+	if len(values) != len(want) {
+		t.Fatalf("got %q, want %d colored values", buf.String(), len(want))
+	}
+	for i, v := range values {
+		if got := v[1]; got != "\x1b["+want[i]+"m" {
+			t.Errorf("%d: got %q, want %q", i, got, want[i])
+		}
+	}
+}